@@ -0,0 +1,103 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import "sync/atomic"
+
+// AtomicTree is a Binary Indexed Tree that can be updated and queried from
+// many goroutines without locks. Every partial sum is read and written with
+// atomic operations. As its partial sums serve as counters that are updated
+// by many goroutines, the numbers are int64, such that the partial sums near
+// the root, which every Add touches, do not wrap around at 2^31.
+//
+// Add is a sequence of atomic additions on the partial sums of its update
+// path, and Sum is a sum of atomic loads of the partial sums of its query
+// path. Each load is linearizable, and the query path of index i shares
+// exactly one partial sum with the update path of every index j <= i. Hence,
+// a Sum that runs concurrently with an Add observes either the full value
+// added or none of it. A Sum does not observe a consistent snapshot of
+// several concurrent Adds though: it may observe a later Add without an
+// earlier one. Once all Adds have returned, Sum is exact. RangeSum and Number
+// combine several loads from different query paths, and only give the same
+// guarantees per partial sum.
+type AtomicTree []atomic.Int64
+
+// NewAtomicTree creates an AtomicTree of n elements, all set to zero.
+func NewAtomicTree(n int) AtomicTree {
+	if n <= 0 {
+		return AtomicTree{}
+	}
+	return make(AtomicTree, n)
+}
+
+// AtomicTreeFrom creates an AtomicTree from a slice of numbers.
+func AtomicTreeFrom(numbers []int64) AtomicTree {
+	t := NewAtomicTree(len(numbers))
+	for i, v := range numbers {
+		t[i].Store(v)
+	}
+
+	// add every partial sum to its parent, as encode does
+	for i := range t {
+		if j := i | (i + 1); j < len(t) {
+			t[j].Add(t[i].Load())
+		}
+	}
+	return t
+}
+
+// Len returns the number of elements in the tree.
+func (t AtomicTree) Len() int {
+	return len(t)
+}
+
+// Add atomically adds the given value to the relevant partial sums of the
+// number at index i. If the index is outside of the tree boundaries, no
+// value is added.
+func (t AtomicTree) Add(i int, value int64) {
+	for 0 <= i && i < len(t) {
+		t[i].Add(value)
+		i |= i + 1
+	}
+}
+
+// Sum returns the prefix sum at index i of the tree, by atomically loading
+// the relevant partial sums. If i is larger than the largest index of the
+// tree, the prefix sum of the largest index is returned.
+func (t AtomicTree) Sum(i int) int64 {
+	if len(t) <= i {
+		i = len(t) - 1
+	}
+
+	var sum int64
+	for 0 <= i && i < len(t) {
+		sum += t[i].Load()
+		i = i&(i+1) - 1
+	}
+	return sum
+}
+
+// RangeSum returns the sum of the [lo, hi) range, as the difference of
+// two prefix sums.
+func (t AtomicTree) RangeSum(lo, hi int) int64 {
+	if hi <= lo {
+		return 0
+	}
+	return t.Sum(hi-1) - t.Sum(lo-1)
+}
+
+// Number returns the element at index i.
+// If i is outside of the tree, 0 will be returned.
+func (t AtomicTree) Number(i int) int64 {
+	if i < 0 || len(t) <= i {
+		return 0
+	}
+
+	number := t[i].Load()
+	for j, k := i, i&(i+1); k < j && 0 < j && j <= len(t); j &= j - 1 {
+		number -= t[j-1].Load()
+	}
+	return number
+}
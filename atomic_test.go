@@ -0,0 +1,109 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestAtomicTree(t *testing.T) {
+	for i, tc := range testcases {
+		numbers := make([]int64, len(tc.numbers))
+		for j, num := range tc.numbers {
+			numbers[j] = int64(num)
+		}
+
+		tree := AtomicTreeFrom(numbers)
+		if tree.Len() != len(tc.numbers) {
+			t.Errorf("Testcase: %d, length got: %d != want: %d\n", i, tree.Len(), len(tc.numbers))
+		}
+		for j := range tc.numbers {
+			if got, want := tree.Sum(j), int64(tc.sums[j]); got != want {
+				t.Errorf("Testcase: %d, index: %d, sum got: %d != want: %d\n", i, j, got, want)
+			}
+			if got, want := tree.Number(j), numbers[j]; got != want {
+				t.Errorf("Testcase: %d, index: %d, number got: %d != want: %d\n", i, j, got, want)
+			}
+			if got, want := tree.RangeSum(j, j+1), numbers[j]; got != want {
+				t.Errorf("Testcase: %d, index: %d, range got: %d != want: %d\n", i, j, got, want)
+			}
+		}
+	}
+
+	empty := NewAtomicTree(0)
+	empty.Add(0, 1)
+	if empty.Sum(0) != 0 || empty.Number(0) != 0 || empty.RangeSum(0, 1) != 0 {
+		t.Errorf("empty tree got: %d\n", empty.Sum(0))
+	}
+
+	// the partial sums do not wrap around at 2^31
+	tree := NewAtomicTree(8)
+	for i := 0; i < 8; i++ {
+		tree.Add(i, math.MaxInt32)
+	}
+	if got, want := tree.Sum(7), int64(8*math.MaxInt32); got != want {
+		t.Errorf("sum got: %d != want: %d\n", got, want)
+	}
+}
+
+func TestAtomicTreeConcurrent(t *testing.T) {
+	const (
+		n       = 100
+		writers = 16
+		readers = 4
+		adds    = 500
+	)
+
+	tree := NewAtomicTree(n)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for k := 0; k < adds; k++ {
+				tree.Add((w*adds+k*7)%n, 1)
+			}
+		}(w)
+	}
+
+	// As all values added are positive, every partial sum only grows, and
+	// so does every prefix sum observed by a single reader.
+	errs := make(chan int64, readers)
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var prev int64
+			for k := 0; k < adds; k++ {
+				sum := tree.Sum(n - 1)
+				if sum < prev || writers*adds < sum {
+					errs <- sum
+					return
+				}
+				prev = sum
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for sum := range errs {
+		t.Errorf("inconsistent sum: %d\n", sum)
+	}
+
+	if got, want := tree.Sum(n-1), int64(writers*adds); got != want {
+		t.Errorf("sum got: %d != want: %d\n", got, want)
+	}
+	var total int64
+	for i := 0; i < n; i++ {
+		total += tree.Number(i)
+	}
+	if total != writers*adds {
+		t.Errorf("numbers got: %d != want: %d\n", total, writers*adds)
+	}
+}
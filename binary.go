@@ -0,0 +1,109 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// The binary encoding of a tree is stable: data written by any version of
+// this package can be read by all later versions. It consists of a header,
+// the partial sums of the tree, and a checksum:
+//
+//	offset  size  field
+//	0       4     magic "BIT\x00"
+//	4       1     format version, currently 1
+//	5       1     element type, 1 for int32
+//	6       1     byte order of the fields that follow, 0 for little endian
+//	              and 1 for big endian
+//	7       1     reserved, 0
+//	8       8     number of elements n, as a uint64
+//	16      4n    partial sums
+//	16+4n   4     CRC-32 (IEEE) checksum of all preceding bytes
+//
+// Trees are always written in little endian byte order, but both byte orders
+// can be read.
+const (
+	binaryMagic   = "BIT\x00"
+	binaryVersion = 1
+	binaryInt32   = 1
+	binaryLittle  = 0
+	binaryBig     = 1
+	binaryHeader  = 16
+)
+
+var (
+	// ErrEncoding is returned when decoding data that is not a valid
+	// encoding of a tree.
+	ErrEncoding = errors.New("bit: invalid encoding")
+
+	// ErrChecksum is returned when decoding data with a checksum mismatch.
+	ErrChecksum = errors.New("bit: checksum mismatch")
+)
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (t Tree) MarshalBinary() ([]byte, error) {
+	return t.AppendBinary(make([]byte, 0, binaryHeader+4*len(t)+4))
+}
+
+// AppendBinary appends the binary encoding of the tree to b,
+// and returns the extended buffer.
+func (t Tree) AppendBinary(b []byte) ([]byte, error) {
+	start := len(b)
+	b = append(b, binaryMagic...)
+	b = append(b, binaryVersion, binaryInt32, binaryLittle, 0)
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(t)))
+	for _, sum := range t {
+		b = binary.LittleEndian.AppendUint32(b, uint32(sum))
+	}
+	return binary.LittleEndian.AppendUint32(b, crc32.ChecksumIEEE(b[start:])), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// header and checksum are verified before the tree is modified. The backing
+// store of the tree is reused, when large enough.
+func (t *Tree) UnmarshalBinary(data []byte) error {
+	if len(data) < binaryHeader+4 || string(data[:4]) != binaryMagic {
+		return fmt.Errorf("%w: missing header", ErrEncoding)
+	}
+	if data[4] != binaryVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrEncoding, data[4])
+	}
+	if data[5] != binaryInt32 {
+		return fmt.Errorf("%w: unsupported element type %d", ErrEncoding, data[5])
+	}
+
+	var order binary.ByteOrder
+	switch data[6] {
+	case binaryLittle:
+		order = binary.LittleEndian
+	case binaryBig:
+		order = binary.BigEndian
+	default:
+		return fmt.Errorf("%w: unsupported byte order %d", ErrEncoding, data[6])
+	}
+
+	n := order.Uint64(data[8:])
+	if n != uint64(len(data)-binaryHeader-4)/4 || (len(data)-binaryHeader-4)%4 != 0 {
+		return fmt.Errorf("%w: length %d does not match size %d", ErrEncoding, n, len(data))
+	}
+
+	end := len(data) - 4
+	if order.Uint32(data[end:]) != crc32.ChecksumIEEE(data[:end]) {
+		return ErrChecksum
+	}
+
+	if uint64(cap(*t)) < n {
+		*t = make(Tree, n)
+	}
+	*t = (*t)[:n]
+	for i := range *t {
+		(*t)[i] = int32(order.Uint32(data[binaryHeader+4*i:]))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math/rand"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = Tree{}
+	_ encoding.BinaryUnmarshaler = &Tree{}
+)
+
+func TestBinary(t *testing.T) {
+	// the encoding is stable, so it must never change
+	golden := []byte{
+		'B', 'I', 'T', 0, 1, 1, 0, 0,
+		3, 0, 0, 0, 0, 0, 0, 0,
+		1, 0, 0, 0, 3, 0, 0, 0, 0xfd, 0xff, 0xff, 0xff,
+	}
+	golden = binary.LittleEndian.AppendUint32(golden, crc32.ChecksumIEEE(golden))
+	if got, _ := From([]int32{1, 2, -3}).MarshalBinary(); !bytes.Equal(got, golden) {
+		t.Errorf("got: %v != want: %v\n", got, golden)
+	}
+
+	rand.Seed(18)
+	for _, n := range []int{0, 1, 2, 11, 16, 37} {
+		numbers := make([]int32, n)
+		for i := range numbers {
+			numbers[i] = rand.Int31() - rand.Int31()
+		}
+		tree := From(numbers)
+
+		data, err := tree.MarshalBinary()
+		if err != nil {
+			t.Fatalf("n: %d, error: %v\n", n, err)
+		}
+		prefix := []byte("prefix")
+		if appended, _ := tree.AppendBinary(prefix); !bytes.Equal(appended, append(prefix, data...)) {
+			t.Errorf("n: %d, AppendBinary got: %v\n", n, appended)
+		}
+
+		got := New(5)
+		if err := got.UnmarshalBinary(data); err != nil || !equalTree(got, tree) {
+			t.Errorf("n: %d, got: %v != want: %v, error: %v\n", n, got, tree, err)
+		}
+
+		// re-encode in big endian byte order
+		big := append([]byte(nil), data[:binaryHeader]...)
+		big[6] = binaryBig
+		big = binary.BigEndian.AppendUint64(big[:8], uint64(n))
+		for _, sum := range tree {
+			big = binary.BigEndian.AppendUint32(big, uint32(sum))
+		}
+		big = binary.BigEndian.AppendUint32(big, crc32.ChecksumIEEE(big))
+		if err := got.UnmarshalBinary(big); err != nil || !equalTree(got, tree) {
+			t.Errorf("n: %d, big endian got: %v != want: %v, error: %v\n", n, got, tree, err)
+		}
+	}
+
+	corrupt := func(i int, b byte) []byte {
+		data := append([]byte(nil), golden...)
+		data[i] = b
+		return data
+	}
+	for k, tc := range []struct {
+		data []byte
+		err  error
+	}{
+		{nil, ErrEncoding},
+		{golden[:binaryHeader+3], ErrEncoding},
+		{golden[:len(golden)-1], ErrEncoding},
+		{corrupt(0, 'b'), ErrEncoding},
+		{corrupt(4, 2), ErrEncoding},
+		{corrupt(5, 2), ErrEncoding},
+		{corrupt(6, 2), ErrEncoding},
+		{corrupt(8, 2), ErrEncoding},
+		{corrupt(16, 2), ErrChecksum},
+		{corrupt(len(golden)-1, 0), ErrChecksum},
+	} {
+		tree := Tree{7}
+		if err := tree.UnmarshalBinary(tc.data); !errors.Is(err, tc.err) || len(tree) != 1 || tree[0] != 7 {
+			t.Errorf("Testcase: %d, error got: %v != want: %v\n", k, err, tc.err)
+		}
+	}
+}
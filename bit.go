@@ -37,11 +37,7 @@ func From(numbers []int32, reUse ...bool) Tree {
 		t = numbers
 	}
 
-	for i := range t {
-		if j := i | (i + 1); 0 <= j && j < len(t) {
-			t[j] += t[i]
-		}
-	}
+	t.encode(0)
 
 	return t
 }
@@ -52,6 +48,49 @@ func (t *Tree) Reset() {
 	*t = (*t)[:0]
 }
 
+// Truncate shortens the tree to its first n numbers. As a prefix of a Binary
+// Indexed Tree is still a valid tree, this takes constant time. If n is not
+// smaller than the length of the tree, the tree is left unchanged.
+func (t *Tree) Truncate(n int) {
+	if n < 0 {
+		n = 0
+	}
+	if n < len(*t) {
+		*t = (*t)[:n]
+	}
+}
+
+// Pop removes the last number from the tree and returns it. If the
+// tree is empty, 0 is returned.
+func (t *Tree) Pop() int32 {
+	n := len(*t) - 1
+	if n < 0 {
+		return 0
+	}
+
+	number := t.Number(n)
+	*t = (*t)[:n]
+	return number
+}
+
+// Resize changes the length of the tree to n. If n is smaller than the length
+// of the tree, the tree is truncated. Otherwise, the tree is extended with
+// zeros, which takes O(k + log n) time for k zeros.
+func (t *Tree) Resize(n int) {
+	l := len(*t)
+	if n <= l {
+		t.Truncate(n)
+		return
+	}
+
+	if n <= cap(*t) {
+		*t = (*t)[:n]
+	} else {
+		*t = append(*t, make([]int32, n-l)...)
+	}
+	t.zero(l)
+}
+
 // Copy does a deep copy of the src tree. If the dst tree is smaller
 // than src, only part of the BIT is copied, up to the length of dst.
 // Copy returns the number of elements copied.
@@ -61,18 +100,23 @@ func Copy(dst, src Tree) int {
 	}
 
 	n := copy(dst, src)
+	dst.zero(n)
 
-	// compute partial sums for dst[n:]
-	for i := n; 0 <= i && i < len(dst); i++ {
+	return len(dst)
+}
+
+// zero sets the numbers at index lo and beyond to zero, by computing their
+// partial sums from the partial sums of their children.
+func (t Tree) zero(lo int) {
+	for i := lo; 0 <= i && i < len(t); i++ {
 		var num int32
 		j, k := i, i&(i+1)
-		for k < j && 0 < j && j < len(dst) {
-			num += dst[j-1]
+		for k < j && 0 < j && j < len(t) {
+			num += t[j-1]
 			j &= j - 1
 		}
-		dst[i] = num
+		t[i] = num
 	}
-	return len(dst)
 }
 
 // Append adds numbers to the back of the tree.
@@ -93,18 +137,7 @@ func Append(t Tree, number ...int32) Tree {
 	if len(number) > 1 {
 		l := len(t)
 		t = append(t, number...)
-
-		var imin int
-		if 0 < l {
-			imin = 1<<(bits.Len(uint(l))-1) - 1
-		}
-
-		for i := imin; 0 <= i && i < len(t); i++ {
-			j := i | (i + 1)
-			if 0 <= j && l <= j && j < len(t) {
-				t[j] += t[i]
-			}
-		}
+		t.encode(l)
 		return t
 	}
 
@@ -170,19 +203,107 @@ func (t Tree) RangeSum(lo, hi int) int32 {
 	}
 }
 
+// SumMany returns in the out slice the prefix sums at the given indices, as
+// Sum would return them. Work is shared between subsequent indices: only the
+// partial sums on which their prefix sums differ are visited. Indices can be
+// given in any order, but a sorted slice of indices is most efficient. SumMany
+// returns the number of prefix sums written to out.
+func (t Tree) SumMany(indices []int, out []int32) int {
+	n := len(indices)
+	if len(out) < n {
+		n = len(out)
+	}
+
+	var sum int32
+	prev := -1
+	for k := 0; k < n && k < len(indices) && k < len(out); k++ {
+		i := t.bound(indices[k])
+		sum += t.diff(prev, i)
+		out[k], prev = sum, i
+	}
+
+	return n
+}
+
+// RangeSumMany returns in the out slice the sums of the [lo[k], hi[k]) ranges,
+// as RangeSum would return them. Work is shared between subsequent ranges,
+// which is most efficient when the ranges are sorted. RangeSumMany returns
+// the number of sums written to out.
+func (t Tree) RangeSumMany(lo, hi []int, out []int32) int {
+	n := len(lo)
+	if len(hi) < n {
+		n = len(hi)
+	}
+	if len(out) < n {
+		n = len(out)
+	}
+
+	var sumLo, sumHi int32
+	prevLo, prevHi := -1, -1
+	for k := 0; k < n && k < len(lo) && k < len(hi) && k < len(out); k++ {
+		if hi[k]-lo[k] <= 0 {
+			out[k] = 0
+			continue
+		}
+
+		l, h := t.bound(lo[k]-1), t.bound(hi[k]-1)
+		sumLo += t.diff(prevLo, l)
+		sumHi += t.diff(prevHi, h)
+		out[k], prevLo, prevHi = sumHi-sumLo, l, h
+	}
+
+	return n
+}
+
+// bound limits index i to the [-1, len(t)) range. The prefix sum at index -1
+// is zero, while indices beyond the tree have the prefix sum of the last index.
+func (t Tree) bound(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case len(t) <= i:
+		return len(t) - 1
+	}
+	return i
+}
+
+// diff returns the prefix sum at index j minus the prefix sum at index i. The
+// partial sums on both index chains are added until the chains meet. Indices
+// i and j must be in the [-1, len(t)) range.
+func (t Tree) diff(i, j int) int32 {
+	var sum int32
+	for i != j {
+		switch {
+		case i < j && 0 <= j && j < len(t):
+			sum += t[j]
+			j = j&(j+1) - 1
+		case j < i && 0 <= i && i < len(t):
+			sum -= t[i]
+			i = i&(i+1) - 1
+		default:
+			return sum
+		}
+	}
+
+	return sum
+}
+
 // Sums returns the prefix sums of the tree. If the length of the sums slice
 // is too small, Sums fills the slice starting from index 0 and stops when
 // the slice is full. Sums returns the number of elements in the sums slice.
 func (t Tree) Sums(sums []int32) int {
-	for i := range sums {
-		var sum int32
-		j := i
-		// calculate sum[i] prefix sum by adding relevant partial sums
-		for 0 <= j && j < len(t) {
-			sum += t[j]
-			j = j&(j+1) - 1
+	n := copy(sums, t)
+
+	// sums[i] is the partial sum t[i] plus the prefix sum at i&(i+1)-1,
+	// which has already been computed.
+	for i := 0; i < n && i < len(sums); i++ {
+		if j := i&(i+1) - 1; 0 <= j && j < i {
+			sums[i] += sums[j]
 		}
-		sums[i] = sum
+	}
+
+	for i := n; i < len(sums); i++ {
+		sums[i] = 0
 	}
 
 	return len(sums)
@@ -214,13 +335,19 @@ func (t Tree) RangeNumbers(lo int, buf []int32) int {
 		return 0
 	}
 
-	i, j := 0, lo
-	for i < len(buf) && j < len(t) {
-		buf[i] = t.Number(j)
-		i, j = i+1, j+1
+	n := copy(buf, t[lo:])
+
+	// only odd indices hold partial sums that cover more than one number
+	for i := 1 - lo&1; 0 <= i && i < n && i < len(buf); i += 2 {
+		j := lo + i
+		k := j & (j + 1)
+		for k < j && 0 < j && j < len(t) {
+			buf[i] -= t[j-1]
+			j &= j - 1
+		}
 	}
 
-	return i
+	return n
 }
 
 // Numbers returns all numbers in the tree. The caller provides the array
@@ -274,6 +401,73 @@ func (t Tree) Add(i int, value int32) {
 	}
 }
 
+// AddMany adds the deltas to the numbers in the tree at the respective
+// indices. Indices outside of the tree boundaries are skipped. Deltas that
+// reach the same partial sum are merged before being added, so that every
+// partial sum is updated at most once for a sorted slice of indices. Indices
+// can be given in any order, but unsorted indices share fewer updates. When
+// the batch is dense, the tree is rebuilt in linear time instead.
+func (t Tree) AddMany(indices []int, deltas []int32) {
+	n := len(indices)
+	if len(deltas) < n {
+		n = len(deltas)
+	}
+
+	lo := len(t)
+	for k := 0; k < n && k < len(indices); k++ {
+		if i := indices[k]; 0 <= i && i < lo {
+			lo = i
+		}
+	}
+
+	if t.bulk(lo, n) {
+		t.decode(lo)
+		for k := 0; k < n && k < len(indices) && k < len(deltas); k++ {
+			if i := indices[k]; 0 <= i && i < len(t) {
+				t[i] += deltas[k]
+			}
+		}
+		t.encode(lo)
+		return
+	}
+
+	// The first partial sum on the update path of an index, that is larger
+	// than or equal to a next, larger index, is also on the update path of
+	// that next index. Hence, the deltas of all previous indices can be
+	// carried in a single partial sum, until they merge.
+	node, carry, prev := len(t), int32(0), -1
+	for k := 0; k < n && k < len(indices) && k < len(deltas); k++ {
+		i, delta := indices[k], deltas[k]
+		if i < 0 || len(t) <= i {
+			continue
+		}
+
+		// flush the carry up to the update path of index i, or completely
+		// when the indices are not sorted
+		for 0 <= node && node < len(t) && (node < i || i < prev) {
+			t[node] += carry
+			node |= node + 1
+		}
+		prev = i
+
+		if len(t) <= node {
+			node, carry = i, delta
+			continue
+		}
+
+		for 0 <= i && i < node && i < len(t) {
+			t[i] += delta
+			i |= i + 1
+		}
+		carry += delta
+	}
+
+	for 0 <= node && node < len(t) {
+		t[node] += carry
+		node |= node + 1
+	}
+}
+
 // Mul multiplies the number at index i with the given value. If the
 // index is outside of the tree boundaries, no modifications are done.
 func (t Tree) Mul(i int, value int32) int32 {
@@ -319,6 +513,15 @@ func (t Tree) Scale(value int32) {
 // RangeAdd adds a slice of numbers to the numbers in the tree
 // at index i and subsequent indices.
 func (t Tree) RangeAdd(i int, numbers []int32) {
+	if lo, hi := t.clip(i, i+len(numbers)); t.bulk(lo, hi-lo) {
+		t.decode(lo)
+		for j := lo; j < hi; j++ {
+			t[j] += numbers[j-i]
+		}
+		t.encode(lo)
+		return
+	}
+
 	for j := 0; j < len(numbers) && i < len(t); i, j = i+1, j+1 {
 		t.Add(i, numbers[j])
 	}
@@ -327,6 +530,15 @@ func (t Tree) RangeAdd(i int, numbers []int32) {
 // RangeMul multiplies a slice of numbers with the respective numbers
 // in the tree, starting at index i.
 func (t Tree) RangeMul(i int, factors []int32) {
+	if lo, hi := t.clip(i, i+len(factors)); t.bulk(lo, hi-lo) {
+		t.decode(lo)
+		for j := lo; j < hi; j++ {
+			t[j] *= factors[j-i]
+		}
+		t.encode(lo)
+		return
+	}
+
 	for j := 0; j < len(factors) && i < len(t); i, j = i+1, j+1 {
 		t.Mul(i, factors[j])
 	}
@@ -334,6 +546,13 @@ func (t Tree) RangeMul(i int, factors []int32) {
 
 // RangeSet sets a slice of numbers in the tree, starting at index i.
 func (t Tree) RangeSet(i int, numbers []int32) {
+	if lo, hi := t.clip(i, i+len(numbers)); t.bulk(lo, hi-lo) {
+		t.decode(lo)
+		copy(t[lo:hi], numbers[lo-i:])
+		t.encode(lo)
+		return
+	}
+
 	for j := 0; j < len(numbers) && i < len(t); i, j = i+1, j+1 {
 		t.Set(i, numbers[j])
 	}
@@ -384,6 +603,16 @@ func minabs(a, b int32) int32 {
 // the given multiplier. If lo/hi are outside the boundaries of the tree,
 // the [lo, hi) range will be intersected with the tree range.
 func (t Tree) RangeScale(lo, hi int, multiplier int32) {
+	lo, hi = t.clip(lo, hi)
+	if t.bulk(lo, hi-lo) {
+		t.decode(lo)
+		for i := lo; i < hi; i++ {
+			t[i] *= multiplier
+		}
+		t.encode(lo)
+		return
+	}
+
 	for i := lo; i < hi && 0 <= i && i < len(t); i++ {
 		t.Mul(i, multiplier)
 	}
@@ -393,8 +622,142 @@ func (t Tree) RangeScale(lo, hi int, multiplier int32) {
 // smaller than or equal to the given value. In case the tree is empty, -1 is
 // returned.This operation assumes the prefix sums to increase monotonically.
 func (t Tree) SearchSum(value int32) (int, int32) {
+	n, sum := t.search(value, false)
+	return n - 1, sum
+}
+
+// SearchSumLT returns the largest index and corresponding prefix sum that is
+// strictly smaller than the given value. If no prefix sum is smaller than the
+// value, -1 and 0 are returned. This operation assumes the prefix sums to
+// increase monotonically.
+func (t Tree) SearchSumLT(value int32) (int, int32) {
+	n, sum := t.search(value, true)
+	return n - 1, sum
+}
+
+// SearchSumGE returns the smallest index and corresponding prefix sum that is
+// larger than or equal to the given value. If no prefix sum is large enough,
+// the length and the total sum of the tree are returned. This operation
+// assumes the prefix sums to increase monotonically.
+func (t Tree) SearchSumGE(value int32) (int, int32) {
+	n, sum := t.search(value, true)
+	return n, sum + t.Number(n)
+}
+
+// SearchSumGT returns the smallest index and corresponding prefix sum that is
+// strictly larger than the given value. If no prefix sum is large enough, the
+// length and the total sum of the tree are returned. This operation assumes
+// the prefix sums to increase monotonically.
+func (t Tree) SearchSumGT(value int32) (int, int32) {
+	n, sum := t.search(value, false)
+	return n, sum + t.Number(n)
+}
+
+// SearchRangeSum returns the smallest hi, and the corresponding sum of the
+// [lo, hi) range, for which the range sum is larger than or equal to the given
+// value. If the value is not positive, the empty [lo, lo) range is returned.
+// If the numbers from index lo onwards add up to less than the value, the
+// length of the tree and the sum of those numbers are returned. The returned
+// index is never below lo, also when the prefix sums up to lo overflow. This
+// operation assumes the numbers in the tree to be non-negative.
+func (t Tree) SearchRangeSum(lo int, value int32) (int, int32) {
+	switch {
+	case lo < 0:
+		lo = 0
+	case len(t) < lo:
+		lo = len(t)
+	}
+
+	if value <= 0 || lo == len(t) {
+		return lo, 0
+	}
+
+	// Sum holds the prefix sum at index n-1 minus the one at index lo-1, which
+	// wraps around when the prefix sums overflow. Prefixes up to lo are always
+	// taken, such that n never drops below lo. Beyond lo, sum is the exact range
+	// sum modulo 2^32, which is compared as an unsigned value.
+	n, sum := 0, -t.Sum(lo-1)
+	for hi := 1 << (bits.Len(uint(len(t))) - 1); hi != 0; hi >>= 1 {
+		if m := n + hi; 0 < m && m <= len(t) {
+			if m <= lo || uint32(sum+t[m-1]) < uint32(value) {
+				n = m
+				sum += t[m-1]
+			}
+		}
+	}
+
+	if n == len(t) {
+		return n, sum
+	}
+	return n + 1, sum + t.Number(n)
+}
+
+// SearchFunc returns the smallest index i, and the corresponding prefix sum,
+// for which pred(i, sum) returns true, where sum is the prefix sum at index i.
+// The predicate must be monotone: once true, it must remain true for all larger
+// indices. The tree is descended one power of two at a time, such that pred is
+// called O(log n) times, with candidate indices that are not visited in order.
+// If pred is false for all indices, the length and the total sum of the tree
+// are returned. SearchSumGT(v) is equivalent to a SearchFunc with a predicate
+// that returns sum > v, and SearchSum(v) returns the index just before that.
+func (t Tree) SearchFunc(pred func(i int, sum int32) bool) (int, int32) {
 	if len(t) == 0 {
-		return -1, 0
+		return 0, 0
+	}
+
+	var sum int32
+	lo, hi := 0, 1<<(bits.Len(uint(len(t)))-1)
+	for hi != 0 {
+		if m := lo + hi; 0 < m && m <= len(t) {
+			if !pred(m-1, sum+t[m-1]) {
+				lo += hi
+				sum += t[m-1]
+			}
+		}
+		hi >>= 1
+	}
+
+	return lo, sum + t.Number(lo)
+}
+
+// NextNonZero returns the smallest index, larger than or equal to i, that
+// holds a non-zero number. If there is no such index, the length of the tree
+// is returned. This operation assumes the numbers to be non-negative.
+func (t Tree) NextNonZero(i int) int {
+	if len(t) <= i {
+		return len(t)
+	}
+	if i < 0 {
+		i = 0
+	}
+
+	j, _ := t.SearchSumGT(t.Sum(i - 1))
+	return j
+}
+
+// PrevNonZero returns the largest index, smaller than or equal to i, that
+// holds a non-zero number. If there is no such index, -1 is returned. This
+// operation assumes the numbers to be non-negative.
+func (t Tree) PrevNonZero(i int) int {
+	if i < 0 {
+		return -1
+	}
+
+	sum := t.Sum(i)
+	if sum <= 0 {
+		return -1
+	}
+
+	j, _ := t.SearchSumLT(sum)
+	return j + 1
+}
+
+// search returns the number of leading prefix sums that are smaller than, or
+// when not strict, equal to the given value, and the last of those prefix
+// sums. The tree is descended one power of two at a time.
+func (t Tree) search(value int32, strict bool) (int, int32) {
+	if len(t) == 0 {
+		return 0, 0
 	}
 
 	lo, hi := 0, 1<<(bits.Len(uint(len(t)))-1)
@@ -402,7 +765,7 @@ func (t Tree) SearchSum(value int32) (int, int32) {
 
 	for hi != 0 {
 		if m := lo + hi; 0 < m && m <= len(t) {
-			if toSearch >= t[m-1] {
+			if toSearch > t[m-1] || !strict && toSearch == t[m-1] {
 				lo += hi
 				toSearch -= t[m-1]
 			}
@@ -410,5 +773,64 @@ func (t Tree) SearchSum(value int32) (int, int32) {
 		hi >>= 1
 	}
 
-	return lo - 1, value - toSearch
+	return lo, value - toSearch
+}
+
+// clip intersects the [lo, hi) range with the index range of the tree.
+func (t Tree) clip(lo, hi int) (int, int) {
+	if lo < 0 {
+		lo = 0
+	}
+	if len(t) < hi {
+		hi = len(t)
+	}
+	return lo, hi
+}
+
+// bulk reports whether k updates of numbers at index lo or beyond are cheaper
+// by decoding the tree from index lo onwards, updating the numbers, and
+// rebuilding the partial sums, than by updating the numbers one at a time.
+// Decoding and rebuilding visits every partial sum from lo onwards twice,
+// where a single update visits up to log(len(t)) partial sums, but at about
+// a quarter of the cost per visit.
+func (t Tree) bulk(lo, k int) bool {
+	if lo < 0 || len(t) <= lo || k <= 0 {
+		return false
+	}
+	return 8*(len(t)-lo) < k*bits.Len(uint(len(t)))
+}
+
+// decode replaces, in place, the partial sums at index lo and beyond
+// by the numbers they represent. The partial sums below lo are left
+// untouched. The tree is invalid until it is rebuilt with encode.
+func (t Tree) decode(lo int) {
+	if lo < 0 {
+		lo = 0
+	}
+
+	// only odd indices hold partial sums that cover more than one number
+	i := len(t)&^1 - 1
+	for lo <= i && 0 < i && i < len(t) {
+		k := i & (i + 1)
+		for j := i; k < j && 0 < j && j < len(t); j &= j - 1 {
+			t[i] -= t[j-1]
+		}
+		i -= 2
+	}
+}
+
+// encode is the inverse of decode. It rebuilds, in place, the partial sums
+// at index lo and beyond from the numbers stored there. The partial sums
+// below lo must be valid.
+func (t Tree) encode(lo int) {
+	var imin int
+	if 0 < lo {
+		imin = 1<<(bits.Len(uint(lo))-1) - 1
+	}
+
+	for i := imin; 0 <= i && i < len(t); i++ {
+		if j := i | (i + 1); lo <= j && j < len(t) {
+			t[j] += t[i]
+		}
+	}
 }
@@ -5,7 +5,9 @@
 package bit
 
 import (
+	"math"
 	"math/rand"
+	"sort"
 	"testing"
 )
 
@@ -41,17 +43,23 @@ func TestNew(t *testing.T) {
 	tree := New()
 	Len(tree)
 	tree.Reset()
+	tree.Truncate(0)
+	tree.Pop()
+	tree.Resize(0)
 	dst := New()
 	Copy(dst, tree)
 	tree = Append(tree)
 	tree.Sum(0)
 	tree.RangeSum(0, 0)
+	tree.SumMany([]int{0}, make([]int32, 1))
+	tree.RangeSumMany([]int{0}, []int{1}, make([]int32, 1))
 	tree.Sums(nil)
 	tree.Number(0)
 	tree.RangeNumbers(0, nil)
 	tree.Numbers(nil)
 	tree.Set(0, -5)
 	tree.Add(0, -5)
+	tree.AddMany([]int{0}, []int32{-5})
 	tree.Mul(0, -5)
 	tree.Shift(-5)
 	tree.Scale(-5)
@@ -61,6 +69,13 @@ func TestNew(t *testing.T) {
 	tree.RangeMul(0, nil)
 	tree.RangeSet(0, nil)
 	tree.SearchSum(5)
+	tree.SearchSumLT(5)
+	tree.SearchSumGE(5)
+	tree.SearchSumGT(5)
+	tree.SearchRangeSum(0, 5)
+	tree.SearchFunc(func(int, int32) bool { return true })
+	tree.NextNonZero(0)
+	tree.PrevNonZero(0)
 }
 
 func TestFrom(t *testing.T) {
@@ -112,6 +127,82 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+func TestTruncateAndPop(t *testing.T) {
+	for i, tc := range testcases {
+		for l := -1; l <= len(tc.numbers)+1; l++ {
+			tree := From(tc.numbers)
+			tree.Truncate(l)
+
+			want := l
+			if want < 0 {
+				want = 0
+			}
+			if len(tc.numbers) < want {
+				want = len(tc.numbers)
+			}
+			if Len(tree) != want {
+				t.Errorf("Testcase: %d, length got: %d != want: %d\n", i, Len(tree), want)
+			}
+			for j, v := range From(tc.numbers[:want]) {
+				if tree[j] != v {
+					t.Errorf("Testcase: %d, index: %d, got: %d != want: %d\n", i, j, tree[j], v)
+				}
+			}
+		}
+
+		tree := From(tc.numbers)
+		for j := len(tc.numbers) - 1; 0 <= j; j-- {
+			if got := tree.Pop(); got != tc.numbers[j] || Len(tree) != j {
+				t.Errorf(
+					"Testcase: %d, index: %d, got: %d != want: %d\n",
+					i, j, got, tc.numbers[j],
+				)
+			}
+		}
+		if got := tree.Pop(); got != 0 || Len(tree) != 0 {
+			t.Errorf("Testcase: %d, got: %d != want: 0\n", i, got)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	for i, tc := range testcases {
+		for _, l := range []int{0, 1, 7, 16, 33} {
+			numbers := make([]int32, l)
+			copy(numbers, tc.numbers)
+			want := From(numbers)
+
+			tree := From(tc.numbers)
+			tree.Resize(l)
+			for j := range want {
+				if tree[j] != want[j] {
+					t.Errorf(
+						"Testcase: %d, length: %d, index: %d, got: %d != want: %d\n",
+						i, l, j, tree[j], want[j],
+					)
+				}
+			}
+
+			// grow within the capacity, which holds stale partial sums
+			tree = From(tc.numbers)
+			tree.Truncate(len(tc.numbers) / 2)
+			tree.Resize(l)
+			copy(numbers, tc.numbers[:len(tc.numbers)/2])
+			for j := len(tc.numbers) / 2; j < l; j++ {
+				numbers[j] = 0
+			}
+			for j, v := range From(numbers) {
+				if tree[j] != v {
+					t.Errorf(
+						"Testcase: %d, length: %d, index: %d, got: %d != want: %d\n",
+						i, l, j, tree[j], v,
+					)
+				}
+			}
+		}
+	}
+}
+
 func TestNewAndSet(t *testing.T) {
 	for i, tc := range testcases {
 		tree := New(len(tc.numbers))
@@ -195,6 +286,65 @@ func TestRangeSum(t *testing.T) {
 	}
 }
 
+func TestSumMany(t *testing.T) {
+	for i, tc := range testcases {
+		tree := From(tc.numbers)
+
+		indices := []int{-3, -1}
+		for j := range tc.numbers {
+			indices = append(indices, j)
+		}
+		for j := len(tc.numbers) + 2; j >= -2; j -= 3 {
+			indices = append(indices, j)
+		}
+
+		out := make([]int32, len(indices))
+		if n := tree.SumMany(indices, out); n != len(indices) {
+			t.Errorf("Testcase: %d, got: %d != want: %d\n", i, n, len(indices))
+		}
+		for k, j := range indices {
+			if got, want := out[k], tree.Sum(j); got != want {
+				t.Errorf(
+					"Testcase: %d, index: %d, got: %d != want: %d\n",
+					i, j, got, want,
+				)
+			}
+		}
+	}
+}
+
+func TestRangeSumMany(t *testing.T) {
+	for i, tc := range testcases {
+		tree := From(tc.numbers)
+
+		var lo, hi []int
+		for l := -2; l <= len(tc.numbers)+1; l++ {
+			for h := l - 1; h <= len(tc.numbers)+2; h++ {
+				lo, hi = append(lo, l), append(hi, h)
+			}
+		}
+
+		out := make([]int32, len(lo))
+		if n := tree.RangeSumMany(lo, hi, out); n != len(lo) {
+			t.Errorf("Testcase: %d, got: %d != want: %d\n", i, n, len(lo))
+		}
+		for k := range lo {
+			var want int32
+			for j, num := range tc.numbers {
+				if lo[k] <= j && j < hi[k] {
+					want += num
+				}
+			}
+			if out[k] != want {
+				t.Errorf(
+					"Testcase: %d, range: [%d, %d), got: %d != want: %d\n",
+					i, lo[k], hi[k], out[k], want,
+				)
+			}
+		}
+	}
+}
+
 func TestRangeNumbers(t *testing.T) {
 	buf := make([]int32, 1)
 	for i, tc := range testcases {
@@ -209,6 +359,22 @@ func TestRangeNumbers(t *testing.T) {
 				)
 			}
 		}
+
+		for lo := range tc.numbers {
+			all := make([]int32, len(tc.numbers)+1)
+			n := tree.RangeNumbers(lo, all)
+			if want := len(tc.numbers) - lo; n != want {
+				t.Errorf("Testcase: %d, lo: %d, got: %d != want: %d\n", i, lo, n, want)
+			}
+			for j, want := range tc.numbers[lo:] {
+				if all[j] != want {
+					t.Errorf(
+						"Testcase: %d, lo: %d, index: %d, got: %d != want: %d\n",
+						i, lo, j, all[j], want,
+					)
+				}
+			}
+		}
 	}
 }
 
@@ -359,6 +525,46 @@ func TestAdd(t *testing.T) {
 	}
 }
 
+func TestAddMany(t *testing.T) {
+	const n = 37
+
+	numbers := make([]int32, n)
+	rand.Seed(18)
+	for i := range numbers {
+		numbers[i] = rand.Int31n(100) - 50
+	}
+
+	for k := 0; k < 4*n; k++ {
+		indices := make([]int, k)
+		deltas := make([]int32, k)
+		for j := range indices {
+			indices[j] = rand.Intn(n+4) - 2
+			deltas[j] = rand.Int31n(20) - 10
+		}
+
+		for _, sorted := range []bool{false, true} {
+			if sorted {
+				sort.Ints(indices)
+			}
+
+			tree, want := From(numbers), From(numbers)
+			tree.AddMany(indices, deltas)
+			for j, i := range indices {
+				want.Add(i, deltas[j])
+			}
+
+			for i := range want {
+				if tree[i] != want[i] {
+					t.Errorf(
+						"k: %d, sorted: %t, index: %d, got: %d != want: %d\n",
+						k, sorted, i, tree[i], want[i],
+					)
+				}
+			}
+		}
+	}
+}
+
 func TestMul(t *testing.T) {
 	const x = 8
 
@@ -431,6 +637,161 @@ func TestSearchSum(t *testing.T) {
 	}
 }
 
+func TestSearchSumVariants(t *testing.T) {
+	variants := []struct {
+		name   string
+		search func(tree Tree, value int32) (int, int32)
+		match  func(sum, value int32) bool
+		last   bool
+	}{
+		{"SearchSum", Tree.SearchSum, func(s, v int32) bool { return s <= v }, true},
+		{"SearchSumLT", Tree.SearchSumLT, func(s, v int32) bool { return s < v }, true},
+		{"SearchSumGE", Tree.SearchSumGE, func(s, v int32) bool { return s >= v }, false},
+		{"SearchSumGT", Tree.SearchSumGT, func(s, v int32) bool { return s > v }, false},
+	}
+
+	for i, tc := range testcases {
+		tree := From(tc.numbers)
+		total := tree.Sum(len(tc.numbers))
+
+		for _, vt := range variants {
+			for value := int32(-2); value <= total+2; value++ {
+				want, wsum := -1, int32(0)
+				if !vt.last {
+					want, wsum = len(tc.sums), total
+				}
+				for j, sum := range tc.sums {
+					if vt.match(sum, value) {
+						want, wsum = j, sum
+						if !vt.last {
+							break
+						}
+					}
+				}
+
+				if got, gsum := vt.search(tree, value); got != want || gsum != wsum {
+					t.Errorf(
+						"Testcase: %d, %s(%d), got: (%d, %d) != want: (%d, %d)\n",
+						i, vt.name, value, got, gsum, want, wsum,
+					)
+				}
+			}
+		}
+	}
+}
+
+func TestSearchRangeSum(t *testing.T) {
+	for i, tc := range testcases[2:] {
+		tree := From(tc.numbers)
+		total := tree.Sum(len(tc.numbers))
+
+		for lo := -1; lo <= len(tc.numbers)+1; lo++ {
+			for value := int32(-1); value <= total+1; value++ {
+				want, wsum := lo, int32(0)
+				if want < 0 {
+					want = 0
+				}
+				if len(tc.numbers) < want {
+					want = len(tc.numbers)
+				}
+				for value > 0 && wsum < value && want < len(tc.numbers) {
+					wsum += tc.numbers[want]
+					want++
+				}
+
+				if got, gsum := tree.SearchRangeSum(lo, value); got != want || gsum != wsum {
+					t.Errorf(
+						"Testcase: %d, lo: %d, value: %d, got: (%d, %d) != want: (%d, %d)\n",
+						i, lo, value, got, gsum, want, wsum,
+					)
+				}
+			}
+		}
+	}
+
+	// the prefix sums overflow, the range sums do not
+	tree := From([]int32{math.MaxInt32, 1, math.MaxInt32 - 2, 2})
+	if got, gsum := tree.SearchRangeSum(2, math.MaxInt32); got != 4 || gsum != math.MaxInt32 {
+		t.Errorf("got: (%d, %d) != want: (4, %d)\n", got, gsum, int32(math.MaxInt32))
+	}
+
+	// the prefix sums before lo overflow
+	tree = From([]int32{0, 0, 0, 0, math.MaxInt32, math.MaxInt32, 5, 0})
+	for lo := 6; lo <= 8; lo++ {
+		want, wsum := 7, int32(5)
+		if lo != 6 {
+			want, wsum = 8, 0
+		}
+		if got, gsum := tree.SearchRangeSum(lo, 1); got != want || gsum != wsum {
+			t.Errorf("lo: %d, got: (%d, %d) != want: (%d, %d)\n", lo, got, gsum, want, wsum)
+		}
+	}
+}
+
+func TestSearchFunc(t *testing.T) {
+	for i, tc := range testcases[2:] {
+		tree := From(tc.numbers)
+		total := tree.Sum(len(tc.numbers))
+
+		for value := int32(-1); value <= total+1; value++ {
+			want, wsum := tree.SearchSumGT(value)
+			got, gsum := tree.SearchFunc(func(_ int, sum int32) bool {
+				return sum > value
+			})
+			if got != want || gsum != wsum {
+				t.Errorf(
+					"Testcase: %d, value: %d, got: (%d, %d) != want: (%d, %d)\n",
+					i, value, got, gsum, want, wsum,
+				)
+			}
+		}
+
+		// the first index at which the prefix sum exceeds half of the total
+		got, gsum := tree.SearchFunc(func(_ int, sum int32) bool {
+			return 2*sum > total
+		})
+		if 2*gsum <= total || 0 < got && 2*tc.sums[got-1] > total {
+			t.Errorf("Testcase: %d, got: (%d, %d), total: %d\n", i, got, gsum, total)
+		}
+
+		// the predicate can use the index as well
+		for j := range tc.numbers {
+			if got, _ := tree.SearchFunc(func(i int, _ int32) bool { return j <= i }); got != j {
+				t.Errorf("Testcase: %d, got: %d != want: %d\n", i, got, j)
+			}
+		}
+	}
+}
+
+func TestNonZero(t *testing.T) {
+	numbers := []int32{0, 0, 3, 0, 1, 1, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 5, 0}
+	for l := 0; l <= len(numbers); l++ {
+		tree := From(numbers[:l])
+
+		for i := -2; i <= l+1; i++ {
+			next := l
+			for j := l - 1; 0 <= j && i <= j; j-- {
+				if numbers[j] != 0 {
+					next = j
+				}
+			}
+			if got := tree.NextNonZero(i); got != next {
+				t.Errorf("length: %d, NextNonZero(%d) got: %d != want: %d\n", l, i, got, next)
+			}
+
+			prev := -1
+			for j := 0; j < l && j <= i; j++ {
+				if numbers[j] != 0 {
+					prev = j
+				}
+			}
+			if got := tree.PrevNonZero(i); got != prev {
+				t.Errorf("length: %d, PrevNonZero(%d) got: %d != want: %d\n", l, i, got, prev)
+			}
+		}
+	}
+}
+
 func TestNumbers(t *testing.T) {
 	for i, tc := range testcases {
 		tree := From(tc.numbers)
@@ -451,8 +812,12 @@ func TestNumbers(t *testing.T) {
 func TestSums(t *testing.T) {
 	for i, tc := range testcases {
 		tree := From(tc.numbers)
-		sums := make([]int32, len(tc.numbers))
+		sums := make([]int32, len(tc.numbers)+1)
+		sums[len(tc.numbers)] = -1
 		tree.Sums(sums)
+		if sums[len(tc.numbers)] != 0 {
+			t.Errorf("Testcase: %d, got: %d != want: 0\n", i, sums[len(tc.numbers)])
+		}
 		for j, sum := range tc.sums {
 			if sums[j] != sum {
 				t.Errorf(
@@ -464,6 +829,109 @@ func TestSums(t *testing.T) {
 	}
 }
 
+func TestRangeBulk(t *testing.T) {
+	rand.Seed(18)
+
+	ops := []struct {
+		name  string
+		apply func(tree Tree, lo int, values []int32)
+		want  func(number, value int32) int32
+	}{
+		{
+			"RangeAdd",
+			func(tree Tree, lo int, values []int32) { tree.RangeAdd(lo, values) },
+			func(number, value int32) int32 { return number + value },
+		},
+		{
+			"RangeMul",
+			func(tree Tree, lo int, values []int32) { tree.RangeMul(lo, values) },
+			func(number, value int32) int32 { return number * value },
+		},
+		{
+			"RangeSet",
+			func(tree Tree, lo int, values []int32) { tree.RangeSet(lo, values) },
+			func(number, value int32) int32 { return value },
+		},
+		{
+			"RangeScale",
+			func(tree Tree, lo int, values []int32) {
+				tree.RangeScale(lo, lo+len(values), values[0])
+			},
+			func(number, value int32) int32 { return number * value },
+		},
+	}
+
+	// The tree is only rebuilt for at least 256 numbers, such that the
+	// large tree covers the rebuild path, also when starting near its end.
+	for _, n := range []int{37, 1000} {
+		numbers := make([]int32, n)
+		for i := range numbers {
+			numbers[i] = rand.Int31n(100) - 50
+		}
+
+		values := make([]int32, n+4)
+		for i := range values {
+			values[i] = -3
+		}
+
+		bounds := func(lo int) []int {
+			his := []int{lo + 1, lo + 3, n - 1, n, n + 2}
+			if n < 256 {
+				his = his[:0]
+				for hi := lo + 1; hi <= n+2; hi++ {
+					his = append(his, hi)
+				}
+			}
+			return his
+		}
+		los := []int{-2, 0, 1, n / 2, n - 300, n - 5, n - 1, n}
+		if n < 256 {
+			los = los[:0]
+			for lo := -2; lo <= n; lo++ {
+				los = append(los, lo)
+			}
+		}
+
+		for _, op := range ops {
+			var rebuilt int
+			for _, lo := range los {
+				for _, hi := range bounds(lo) {
+					if hi <= lo {
+						continue
+					}
+
+					tree := From(numbers)
+					if clo, chi := tree.clip(lo, hi); tree.bulk(clo, chi-clo) {
+						rebuilt++
+					}
+					op.apply(tree, lo, values[:hi-lo])
+
+					want := make([]int32, n)
+					copy(want, numbers)
+					for i := range want {
+						if lo <= i && i < hi {
+							want[i] = op.want(want[i], -3)
+						}
+					}
+
+					for i, v := range From(want) {
+						if tree[i] != v {
+							t.Fatalf(
+								"%s n: %d [%d, %d), index: %d, got: %d != want: %d\n",
+								op.name, n, lo, hi, i, tree[i], v,
+							)
+						}
+					}
+				}
+			}
+
+			if 256 <= n && rebuilt == 0 {
+				t.Errorf("%s n: %d, the tree was never rebuilt\n", op.name, n)
+			}
+		}
+	}
+}
+
 func TestLargerSamples(t *testing.T) {
 	const (
 		n       = 10
@@ -818,6 +1286,21 @@ func BenchmarkTree(b *testing.B) {
 		}
 	})
 
+	b.Run("SumMany", func(b *testing.B) {
+		tree := From(in1)
+		indices := make([]int, Len(tree))
+		for j := range indices {
+			indices[j] = j
+		}
+		sums := make([]int32, Len(tree))
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			tree.SumMany(indices, sums)
+		}
+	})
+
 	b.Run("Sums", func(b *testing.B) {
 		tree := From(in1)
 		sums := make([]int32, Len(tree))
@@ -879,6 +1362,20 @@ func BenchmarkTree(b *testing.B) {
 		}
 	})
 
+	b.Run("AddMany", func(b *testing.B) {
+		tree := From(in1)
+		indices := make([]int, len(in1))
+		for j := range indices {
+			indices[j] = j
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			tree.AddMany(indices, in1)
+		}
+	})
+
 	b.Run("RangeAdd", func(b *testing.B) {
 		tree := From(in1)
 		buf := make([]int32, 100)
@@ -892,6 +1389,17 @@ func BenchmarkTree(b *testing.B) {
 		}
 	})
 
+	b.Run("RangeAdd-AllNums", func(b *testing.B) {
+		tree := From(in1)
+		buf := make([]int32, len(in1))
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			tree.RangeAdd(0, buf)
+		}
+	})
+
 	b.Run("RangeShift", func(b *testing.B) {
 		tree := From(in1)
 		b.ReportAllocs()
@@ -0,0 +1,95 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"cmp"
+	"slices"
+)
+
+// IndexedTree is a Binary Indexed Tree over a fixed set of ordered keys, such
+// as prices, timestamps or strings. Keys are mapped to the indices of a
+// regular Tree by binary search, such that all operations take O(log n) time.
+type IndexedTree[K cmp.Ordered] struct {
+	keys []K
+	tree Tree
+}
+
+// NewIndexedTree creates an IndexedTree over the given keys, with all numbers
+// set to zero. The keys must be sorted in increasing order and be unique. The
+// tree keeps a reference to the keys slice, which must not be modified.
+func NewIndexedTree[K cmp.Ordered](keys []K) *IndexedTree[K] {
+	return &IndexedTree[K]{keys: keys, tree: New(len(keys))}
+}
+
+// index returns the index of the given key, and whether the key is present.
+func (t *IndexedTree[K]) index(key K) (int, bool) {
+	return slices.BinarySearch(t.keys, key)
+}
+
+// upper returns the number of keys that are smaller than or equal to key.
+func (t *IndexedTree[K]) upper(key K) int {
+	i, found := t.index(key)
+	if found {
+		i++
+	}
+	return i
+}
+
+// Len returns the number of keys in the tree.
+func (t *IndexedTree[K]) Len() int {
+	return len(t.keys)
+}
+
+// Add adds the given value to the number of the given key. If the key
+// is not one of the keys of the tree, no value is added.
+func (t *IndexedTree[K]) Add(key K, value int32) {
+	if i, found := t.index(key); found {
+		t.tree.Add(i, value)
+	}
+}
+
+// Set sets the number of the given key. If the key is not one
+// of the keys of the tree, no updates are made.
+func (t *IndexedTree[K]) Set(key K, number int32) {
+	if i, found := t.index(key); found {
+		t.tree.Set(i, number)
+	}
+}
+
+// Number returns the number of the given key. If the key is not one
+// of the keys of the tree, 0 will be returned.
+func (t *IndexedTree[K]) Number(key K) int32 {
+	if i, found := t.index(key); found {
+		return t.tree.Number(i)
+	}
+	return 0
+}
+
+// SumUpTo returns the sum of the numbers of all keys that are smaller than
+// or equal to the given key. The key does not need to be one of the keys
+// of the tree.
+func (t *IndexedTree[K]) SumUpTo(key K) int32 {
+	return t.tree.Rank(t.upper(key))
+}
+
+// RangeSum returns the sum of the numbers of all keys in the [lo, hi) range.
+// The bounds do not need to be keys of the tree.
+func (t *IndexedTree[K]) RangeSum(lo, hi K) int32 {
+	l, _ := t.index(lo)
+	h, _ := t.index(hi)
+	return t.tree.RangeSum(l, h)
+}
+
+// SearchSum returns the largest key and corresponding sum up to that key that
+// is smaller than or equal to the given value. In case no sum is small enough,
+// ok is false. This operation assumes the numbers to be non-negative.
+func (t *IndexedTree[K]) SearchSum(value int32) (key K, sum int32, ok bool) {
+	i, sum := t.tree.SearchSum(value)
+	if i < 0 {
+		return key, 0, false
+	}
+	return t.keys[i], sum, true
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import "testing"
+
+func TestIndexedTree(t *testing.T) {
+	empty := NewIndexedTree[string](nil)
+	empty.Add("a", 1)
+	if _, _, ok := empty.SearchSum(0); ok || empty.Len() != 0 || empty.SumUpTo("z") != 0 {
+		t.Errorf("empty tree got: %t\n", ok)
+	}
+
+	keys := []float64{-2.5, -1, 0, 0.5, 3, 7.25, 100}
+	numbers := []int32{1, 0, 4, 2, 0, 3, 5}
+
+	tree := NewIndexedTree(keys)
+	for i, k := range keys {
+		tree.Add(k, numbers[i]+1)
+		tree.Add(k, -1)
+	}
+	tree.Add(1, 10)
+	tree.Set(2, 10)
+	if tree.Len() != len(keys) || tree.Number(1) != 0 || tree.Number(2) != 0 {
+		t.Errorf("keys outside of the tree got: %d, %d\n", tree.Number(1), tree.Number(2))
+	}
+	tree.Set(0.5, 1)
+	numbers[3] = 1
+
+	bounds := []float64{-3, -2.5, -2, -1, 0, 0.25, 0.5, 3, 5, 7.25, 100, 101}
+	for _, lo := range bounds {
+		var upTo int32
+		for i, k := range keys {
+			if k <= lo {
+				upTo += numbers[i]
+			}
+		}
+		if got := tree.SumUpTo(lo); got != upTo {
+			t.Errorf("SumUpTo(%v) got: %d != want: %d\n", lo, got, upTo)
+		}
+
+		for _, hi := range bounds {
+			var want int32
+			for i, k := range keys {
+				if lo <= k && k < hi {
+					want += numbers[i]
+				}
+			}
+			if got := tree.RangeSum(lo, hi); got != want {
+				t.Errorf("RangeSum(%v, %v) got: %d != want: %d\n", lo, hi, got, want)
+			}
+		}
+	}
+
+	for value := int32(-1); value <= 12; value++ {
+		var (
+			wkey float64
+			wsum int32
+			wok  bool
+			sum  int32
+		)
+		for i, k := range keys {
+			if sum += numbers[i]; sum <= value {
+				wkey, wsum, wok = k, sum, true
+			}
+		}
+		if key, sum, ok := tree.SearchSum(value); key != wkey || sum != wsum || ok != wok {
+			t.Errorf(
+				"SearchSum(%d) got: (%v, %d, %t) != want: (%v, %d, %t)\n",
+				value, key, sum, ok, wkey, wsum, wok,
+			)
+		}
+	}
+}
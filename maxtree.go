@@ -0,0 +1,119 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import "math/bits"
+
+// MaxTree is a Binary Indexed Tree that can be searched when the prefix sums
+// do not increase monotonically, e.g. when numbers are negative. Next to every
+// partial sum, it keeps the largest prefix sum within the range of that
+// partial sum, relative to the start of the range. Updates take O(log² n)
+// time to keep both in sync, while searches take O(log n) time.
+type MaxTree struct {
+	tree Tree
+	max  []int32
+}
+
+// NewMaxTree creates a MaxTree of n elements, all set to zero.
+func NewMaxTree(n int) MaxTree {
+	if n < 0 {
+		n = 0
+	}
+	return MaxTree{tree: New(n), max: make([]int32, n)}
+}
+
+// MaxTreeFrom creates a MaxTree from a slice of numbers.
+func MaxTreeFrom(numbers []int32) MaxTree {
+	t := MaxTree{tree: From(numbers), max: make([]int32, len(numbers))}
+	for i := range t.max {
+		t.fix(i)
+	}
+	return t
+}
+
+// fix recomputes the largest prefix sum within the range of the partial sum
+// at index i. The largest prefix sums of its children must be up to date.
+func (t MaxTree) fix(i int) {
+	if i < 0 || len(t.tree) <= i || len(t.max) <= i {
+		return
+	}
+
+	// the prefix sum at index i itself is the partial sum, the prefix
+	// sums within every child are offset by the children to its left
+	best := t.tree[i]
+	before := t.tree[i] - t.tree.Number(i)
+	for j, k := i, i&(i+1); k < j && 0 < j && j <= len(t.max); j &= j - 1 {
+		before -= t.tree[j-1]
+		if s := before + t.max[j-1]; best < s {
+			best = s
+		}
+	}
+	t.max[i] = best
+}
+
+// Len returns the number of elements in the tree.
+func (t MaxTree) Len() int {
+	return len(t.tree)
+}
+
+// Sum returns the prefix sum at index i, as Tree.Sum does.
+func (t MaxTree) Sum(i int) int32 {
+	return t.tree.Sum(i)
+}
+
+// RangeSum returns the sum of the [lo, hi) range, as Tree.RangeSum does.
+func (t MaxTree) RangeSum(lo, hi int) int32 {
+	return t.tree.RangeSum(lo, hi)
+}
+
+// Number returns the element at index i.
+// If i is outside of the tree, 0 will be returned.
+func (t MaxTree) Number(i int) int32 {
+	return t.tree.Number(i)
+}
+
+// Add adds the given value to the number in the tree at index i.
+// If the index is outside of the tree boundaries, no value is added.
+func (t MaxTree) Add(i int, value int32) {
+	t.tree.Add(i, value)
+	for 0 <= i && i < len(t.tree) {
+		t.fix(i)
+		i |= i + 1
+	}
+}
+
+// Set sets a number at a given index. If the index
+// is outside of the tree, no updates are made.
+func (t MaxTree) Set(i int, number int32) {
+	if i < 0 || len(t.tree) <= i {
+		return
+	}
+	t.Add(i, number-t.tree.Number(i))
+}
+
+// SearchSumGE returns the smallest index and corresponding prefix sum that is
+// larger than or equal to the given value, i.e. the first index at which the
+// prefix sum reaches the value. Unlike Tree.SearchSumGE, the prefix sums do
+// not need to increase monotonically. If no prefix sum is large enough, the
+// length and the total sum of the tree are returned.
+func (t MaxTree) SearchSumGE(value int32) (int, int32) {
+	if len(t.tree) == 0 {
+		return 0, 0
+	}
+
+	var sum int32
+	lo, hi := 0, 1<<(bits.Len(uint(len(t.tree)))-1)
+	for hi != 0 {
+		if m := lo + hi; 0 < m && m <= len(t.tree) && m <= len(t.max) {
+			if sum+t.max[m-1] < value {
+				lo += hi
+				sum += t.tree[m-1]
+			}
+		}
+		hi >>= 1
+	}
+
+	return lo, sum + t.tree.Number(lo)
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// searchSumGE returns the first index at which the prefix sum of the
+// numbers reaches the given value, by scanning all prefix sums.
+func searchSumGE(numbers []int32, value int32) (int, int32) {
+	var sum int32
+	for i, num := range numbers {
+		if sum += num; value <= sum {
+			return i, sum
+		}
+	}
+	return len(numbers), sum
+}
+
+func TestMaxTree(t *testing.T) {
+	tree := NewMaxTree(0)
+	tree.Add(0, 1)
+	tree.Set(0, 1)
+	if i, sum := tree.SearchSumGE(1); i != 0 || sum != 0 {
+		t.Errorf("got: (%d, %d) != want: (0, 0)\n", i, sum)
+	}
+
+	rand.Seed(18)
+	for _, n := range []int{1, 2, 11, 16, 37} {
+		numbers := make([]int32, n)
+		for i := range numbers {
+			numbers[i] = rand.Int31n(21) - 10
+		}
+
+		tree := MaxTreeFrom(numbers)
+		updated := NewMaxTree(n)
+		for i, num := range numbers {
+			updated.Set(i, num)
+		}
+
+		for k := 0; k < 4*n; k++ {
+			i, v := rand.Intn(n), rand.Int31n(21)-10
+			numbers[i] += v
+			tree.Add(i, v)
+			updated.Add(i, v)
+
+			if tree.Len() != n || tree.Number(i) != numbers[i] {
+				t.Errorf("n: %d, index: %d, got: %d != want: %d\n", n, i, tree.Number(i), numbers[i])
+			}
+			if got, want := tree.RangeSum(0, i+1), tree.Sum(i); got != want {
+				t.Errorf("n: %d, index: %d, got: %d != want: %d\n", n, i, got, want)
+			}
+
+			for value := int32(-40); value <= 40; value++ {
+				want, wsum := searchSumGE(numbers, value)
+				if got, gsum := tree.SearchSumGE(value); got != want || gsum != wsum {
+					t.Errorf(
+						"n: %d, value: %d, got: (%d, %d) != want: (%d, %d)\n",
+						n, value, got, gsum, want, wsum,
+					)
+				}
+				if got, gsum := updated.SearchSumGE(value); got != want || gsum != wsum {
+					t.Errorf(
+						"n: %d, value: %d, got: (%d, %d) != want: (%d, %d)\n",
+						n, value, got, gsum, want, wsum,
+					)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// The op-log is a sequence of operations. Each operation is encoded as a
+// single opcode byte, followed by its arguments as signed varints, in the
+// order of the parameters of the corresponding Tree method. A slice is
+// encoded as its length, as an unsigned varint, followed by its elements.
+// For AddMany, the length is given once, for the indices and the deltas.
+// The opcodes are part of the format and must never be renumbered.
+const (
+	opSet        = 1
+	opAdd        = 2
+	opMul        = 3
+	opShift      = 4
+	opScale      = 5
+	opRangeShift = 6
+	opRangeScale = 7
+	opAppend     = 8
+	opReset      = 9
+	opRangeAdd   = 10
+	opRangeMul   = 11
+	opRangeSet   = 12
+	opAddMany    = 13
+	opTruncate   = 14
+	opPop        = 15
+	opResize     = 16
+)
+
+// ErrInvalidOp is returned by Replay when the op-log holds an unknown
+// opcode, or an argument that is out of range.
+var ErrInvalidOp = errors.New("bit: invalid op-log operation")
+
+// Recorder wraps a tree and records every update made through it to an
+// op-log. Updates are applied to the tree first, and then written to the
+// op-log, with a single write per operation. After a failed write, updates
+// are still applied, but no longer recorded, and Err returns the error. A
+// Recorder is not safe for concurrent use.
+type Recorder struct {
+	tree *Tree
+	w    io.Writer
+	buf  []byte
+	err  error
+}
+
+// NewRecorder creates a Recorder that updates tree t, and writes the
+// op-log to w.
+func NewRecorder(t *Tree, w io.Writer) *Recorder {
+	return &Recorder{tree: t, w: w}
+}
+
+// Err returns the first error that occurred while writing the op-log.
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+// record writes the operation in the buffer to the op-log.
+func (r *Recorder) record() {
+	if r.err == nil {
+		_, r.err = r.w.Write(r.buf)
+	}
+}
+
+// op starts the encoding of an operation with the given arguments.
+func (r *Recorder) op(op byte, args ...int64) {
+	r.buf = append(r.buf[:0], op)
+	for _, arg := range args {
+		r.buf = binary.AppendVarint(r.buf, arg)
+	}
+}
+
+// list appends a slice of numbers to the encoding of an operation.
+func (r *Recorder) list(numbers []int32) {
+	r.buf = binary.AppendUvarint(r.buf, uint64(len(numbers)))
+	for _, num := range numbers {
+		r.buf = binary.AppendVarint(r.buf, int64(num))
+	}
+}
+
+// Set sets a number at a given index, and records the operation.
+func (r *Recorder) Set(i int, number int32) {
+	r.tree.Set(i, number)
+	r.op(opSet, int64(i), int64(number))
+	r.record()
+}
+
+// Add adds the given value to the number at index i,
+// and records the operation.
+func (r *Recorder) Add(i int, value int32) {
+	r.tree.Add(i, value)
+	r.op(opAdd, int64(i), int64(value))
+	r.record()
+}
+
+// Mul multiplies the number at index i with the given value, and records
+// the operation. The updated number is returned.
+func (r *Recorder) Mul(i int, value int32) int32 {
+	number := r.tree.Mul(i, value)
+	r.op(opMul, int64(i), int64(value))
+	r.record()
+	return number
+}
+
+// Shift increases all numbers in the tree with the given value,
+// and records the operation.
+func (r *Recorder) Shift(value int32) {
+	r.tree.Shift(value)
+	r.op(opShift, int64(value))
+	r.record()
+}
+
+// Scale scales all numbers in the tree with the given factor,
+// and records the operation.
+func (r *Recorder) Scale(value int32) {
+	r.tree.Scale(value)
+	r.op(opScale, int64(value))
+	r.record()
+}
+
+// RangeShift adds the given value to all numbers in the [lo, hi) index
+// range of the tree, and records the operation.
+func (r *Recorder) RangeShift(lo, hi int, value int32) {
+	r.tree.RangeShift(lo, hi, value)
+	r.op(opRangeShift, int64(lo), int64(hi), int64(value))
+	r.record()
+}
+
+// RangeScale scales all numbers in the [lo, hi) range of the tree with
+// the given multiplier, and records the operation.
+func (r *Recorder) RangeScale(lo, hi int, multiplier int32) {
+	r.tree.RangeScale(lo, hi, multiplier)
+	r.op(opRangeScale, int64(lo), int64(hi), int64(multiplier))
+	r.record()
+}
+
+// Append appends the numbers to the tree, and records the operation.
+func (r *Recorder) Append(number ...int32) {
+	*r.tree = Append(*r.tree, number...)
+	r.op(opAppend)
+	r.list(number)
+	r.record()
+}
+
+// RangeAdd adds a slice of numbers to the numbers in the tree at index i
+// and subsequent indices, and records the operation.
+func (r *Recorder) RangeAdd(i int, numbers []int32) {
+	r.tree.RangeAdd(i, numbers)
+	r.op(opRangeAdd, int64(i))
+	r.list(numbers)
+	r.record()
+}
+
+// RangeMul multiplies the numbers in the tree, starting at index i, with a
+// slice of factors, and records the operation.
+func (r *Recorder) RangeMul(i int, factors []int32) {
+	r.tree.RangeMul(i, factors)
+	r.op(opRangeMul, int64(i))
+	r.list(factors)
+	r.record()
+}
+
+// RangeSet sets the numbers in the tree, starting at index i, to a slice
+// of numbers, and records the operation.
+func (r *Recorder) RangeSet(i int, numbers []int32) {
+	r.tree.RangeSet(i, numbers)
+	r.op(opRangeSet, int64(i))
+	r.list(numbers)
+	r.record()
+}
+
+// AddMany adds the deltas to the numbers in the tree at the respective
+// indices, and records the operation. Only as many updates as the shortest
+// of both slices are recorded, as only those are made.
+func (r *Recorder) AddMany(indices []int, deltas []int32) {
+	n := min(len(indices), len(deltas))
+	r.tree.AddMany(indices[:n], deltas[:n])
+	r.op(opAddMany)
+	r.buf = binary.AppendUvarint(r.buf, uint64(n))
+	for _, i := range indices[:n] {
+		r.buf = binary.AppendVarint(r.buf, int64(i))
+	}
+	for _, delta := range deltas[:n] {
+		r.buf = binary.AppendVarint(r.buf, int64(delta))
+	}
+	r.record()
+}
+
+// Truncate shortens the tree to its first n numbers,
+// and records the operation.
+func (r *Recorder) Truncate(n int) {
+	r.tree.Truncate(n)
+	r.op(opTruncate, int64(n))
+	r.record()
+}
+
+// Pop removes the last number from the tree and returns it,
+// and records the operation.
+func (r *Recorder) Pop() int32 {
+	number := r.tree.Pop()
+	r.op(opPop)
+	r.record()
+	return number
+}
+
+// Resize changes the length of the tree to n,
+// and records the operation.
+func (r *Recorder) Resize(n int) {
+	r.tree.Resize(n)
+	r.op(opResize, int64(n))
+	r.record()
+}
+
+// Reset initializes the length of the tree to zero,
+// and records the operation.
+func (r *Recorder) Reset() {
+	r.tree.Reset()
+	r.op(opReset)
+	r.record()
+}
+
+// opReader decodes the arguments of operations from an op-log.
+type opReader struct {
+	r    io.ByteReader
+	buf  []int32 // numbers of the last list
+	ibuf []int   // indices of the last list
+	err  error
+}
+
+// varint returns the next argument, which must be in the [min, max] range.
+// An op-log that ends within an operation is reported as ErrUnexpectedEOF.
+func (o *opReader) varint(min, max int64) int64 {
+	if o.err != nil {
+		return 0
+	}
+
+	v, err := binary.ReadVarint(o.r)
+	switch {
+	case err == io.EOF:
+		o.err = io.ErrUnexpectedEOF
+	case err != nil:
+		o.err = err
+	case v < min || max < v:
+		o.err = ErrInvalidOp
+	}
+	return v
+}
+
+// index returns the next argument as an index.
+func (o *opReader) index() int {
+	return int(o.varint(math.MinInt, math.MaxInt))
+}
+
+// number returns the next argument as a number.
+func (o *opReader) number() int32 {
+	return int32(o.varint(math.MinInt32, math.MaxInt32))
+}
+
+// count returns the next argument as the length of a slice.
+func (o *opReader) count() uint64 {
+	if o.err != nil {
+		return 0
+	}
+
+	n, err := binary.ReadUvarint(o.r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	o.err = err
+	return n
+}
+
+// numbers returns the next n arguments as numbers, in a slice that is only
+// valid up to the next call. The slice grows as its numbers are read, as the
+// count n is not trusted.
+func (o *opReader) numbers(n uint64) []int32 {
+	o.buf = o.buf[:0]
+	for ; 0 < n && o.err == nil; n-- {
+		o.buf = append(o.buf, o.number())
+	}
+	return o.buf
+}
+
+// indices returns the next n arguments as indices, like numbers does.
+func (o *opReader) indices(n uint64) []int {
+	o.ibuf = o.ibuf[:0]
+	for ; 0 < n && o.err == nil; n-- {
+		o.ibuf = append(o.ibuf, o.index())
+	}
+	return o.ibuf
+}
+
+// Replay reads an op-log from r, up to its end, and applies the operations
+// to tree t. Replaying an op-log on a copy of the tree it was recorded from
+// reproduces identical partial sums. If r is not an io.ByteReader, it is
+// buffered, such that Replay may read beyond the end of the op-log. In case
+// of an error, the tree holds the operations that were replayed before it.
+func Replay(r io.Reader, t *Tree) error {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	o := &opReader{r: br}
+
+	for {
+		op, err := br.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case opSet:
+			i, number := o.index(), o.number()
+			if o.err == nil {
+				t.Set(i, number)
+			}
+		case opAdd:
+			i, value := o.index(), o.number()
+			if o.err == nil {
+				t.Add(i, value)
+			}
+		case opMul:
+			i, value := o.index(), o.number()
+			if o.err == nil {
+				t.Mul(i, value)
+			}
+		case opShift:
+			if value := o.number(); o.err == nil {
+				t.Shift(value)
+			}
+		case opScale:
+			if value := o.number(); o.err == nil {
+				t.Scale(value)
+			}
+		case opRangeShift:
+			lo, hi, value := o.index(), o.index(), o.number()
+			if o.err == nil {
+				t.RangeShift(lo, hi, value)
+			}
+		case opRangeScale:
+			lo, hi, multiplier := o.index(), o.index(), o.number()
+			if o.err == nil {
+				t.RangeScale(lo, hi, multiplier)
+			}
+		case opAppend:
+			if numbers := o.numbers(o.count()); o.err == nil {
+				*t = Append(*t, numbers...)
+			}
+		case opRangeAdd:
+			i, numbers := o.index(), o.numbers(o.count())
+			if o.err == nil {
+				t.RangeAdd(i, numbers)
+			}
+		case opRangeMul:
+			i, factors := o.index(), o.numbers(o.count())
+			if o.err == nil {
+				t.RangeMul(i, factors)
+			}
+		case opRangeSet:
+			i, numbers := o.index(), o.numbers(o.count())
+			if o.err == nil {
+				t.RangeSet(i, numbers)
+			}
+		case opAddMany:
+			n := o.count()
+			indices, deltas := o.indices(n), o.numbers(n)
+			if o.err == nil {
+				t.AddMany(indices, deltas)
+			}
+		case opTruncate:
+			if n := o.index(); o.err == nil {
+				t.Truncate(n)
+			}
+		case opPop:
+			t.Pop()
+		case opResize:
+			if n := o.index(); o.err == nil {
+				t.Resize(n)
+			}
+		case opReset:
+			t.Reset()
+		default:
+			return ErrInvalidOp
+		}
+
+		if o.err != nil {
+			return o.err
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// failWriter fails every write after the first n bytes.
+type failWriter struct {
+	n int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.n < len(p) {
+		return 0, io.ErrShortWrite
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestRecorderReplay(t *testing.T) {
+	rand.Seed(18)
+	numbers := make([]int32, 37)
+	for i := range numbers {
+		numbers[i] = rand.Int31n(21) - 10
+	}
+
+	start := From(numbers)
+	tree := From(numbers)
+	var log bytes.Buffer
+	r := NewRecorder(&tree, &log)
+
+	for k := 0; k < 500; k++ {
+		n := len(tree)
+		i, j, v := rand.Intn(n+2)-1, rand.Intn(n+2)-1, rand.Int31n(7)-3
+		values := make([]int32, rand.Intn(n+2))
+		for m := range values {
+			values[m] = rand.Int31n(7) - 3
+		}
+		switch k % 16 {
+		case 0:
+			r.Set(i, v)
+		case 1:
+			r.Add(i, v)
+		case 2:
+			if got, want := r.Mul(i, v), tree.Number(i); got != want {
+				t.Errorf("Mul(%d, %d) got: %d != want: %d\n", i, v, got, want)
+			}
+		case 3:
+			r.Shift(v)
+		case 4:
+			r.Scale(v | 1)
+		case 5:
+			r.RangeShift(min(i, j), max(i, j), v)
+		case 6:
+			r.RangeScale(min(i, j), max(i, j), v)
+		case 7:
+			r.Append(make([]int32, rand.Intn(100))...)
+		case 8:
+			r.Append(v, -v, v)
+		case 9:
+			if k%80 == 9 {
+				r.Reset()
+				r.Append(numbers...)
+			}
+		case 10:
+			r.RangeAdd(i, values)
+		case 11:
+			r.RangeMul(i, values)
+		case 12:
+			r.RangeSet(i, values)
+		case 13:
+			indices := make([]int, len(values)+1)
+			for m := range indices {
+				indices[m] = rand.Intn(n+2) - 1
+			}
+			r.AddMany(indices, values)
+		case 14:
+			r.Truncate(n - rand.Intn(10))
+		case 15:
+			if want, got := tree.Number(n-1), r.Pop(); got != want {
+				t.Errorf("Pop got: %d != want: %d\n", got, want)
+			}
+			r.Resize(n + rand.Intn(20) - 5)
+		}
+	}
+	if r.Err() != nil {
+		t.Fatalf("recorder error: %v\n", r.Err())
+	}
+
+	replayed := append(Tree(nil), start...)
+	if err := Replay(bytes.NewReader(log.Bytes()), &replayed); err != nil {
+		t.Fatalf("replay error: %v\n", err)
+	}
+	if !equalTree(replayed, tree) {
+		t.Fatalf("replay got: %v != want: %v\n", replayed, tree)
+	}
+
+	// a reader without ReadByte is buffered
+	replayed = append(Tree(nil), start...)
+	if err := Replay(io.MultiReader(bytes.NewReader(log.Bytes())), &replayed); err != nil || !equalTree(replayed, tree) {
+		t.Fatalf("buffered replay error: %v\n", err)
+	}
+
+	for _, tc := range []struct {
+		log []byte
+		err error
+	}{
+		{nil, nil},
+		{[]byte{opReset}, nil},
+		{[]byte{0}, ErrInvalidOp},
+		{[]byte{opSet, 2}, io.ErrUnexpectedEOF},
+		{[]byte{opAppend, 2, 2}, io.ErrUnexpectedEOF},
+		{[]byte{opAppend}, io.ErrUnexpectedEOF},
+		{[]byte{opShift, 0xfe, 0xff, 0xff, 0xff, 0x1f}, ErrInvalidOp},
+	} {
+		tree := New(4)
+		if err := Replay(bytes.NewReader(tc.log), &tree); !errors.Is(err, tc.err) {
+			t.Errorf("log: %v, error got: %v != want: %v\n", tc.log, err, tc.err)
+		}
+	}
+
+	// a truncated Append leaves the tree unchanged
+	truncated := []byte{opAppend, 100}
+	for k := 0; k < 70; k++ {
+		truncated = append(truncated, 2)
+	}
+	tree = From([]int32{1, 2, 3})
+	err := Replay(bytes.NewReader(truncated), &tree)
+	if err != io.ErrUnexpectedEOF || !equalTree(tree, From([]int32{1, 2, 3})) {
+		t.Errorf("truncated Append got: %v, error: %v\n", tree, err)
+	}
+
+	w := &failWriter{n: 3}
+	tree = New(4)
+	r = NewRecorder(&tree, w)
+	r.Add(1, 1)
+	r.Add(2, 2)
+	r.Add(3, 3)
+	if r.Err() != io.ErrShortWrite || tree.Sum(3) != 6 || w.n != 0 {
+		t.Errorf("error got: %v, sum got: %d\n", r.Err(), tree.Sum(3))
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// minChunk is the smallest number of elements handed to a single worker.
+// Smaller chunks cost more in goroutine overhead than they gain in speed.
+const minChunk = 1 << 14
+
+// chunkSize returns the power-of-two chunk size used to split n elements
+// over the given number of workers. If workers is not positive, the
+// number of workers defaults to GOMAXPROCS.
+func chunkSize(n, workers int) int {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	size := (n + workers - 1) / workers
+	if size < minChunk {
+		size = minChunk
+	}
+	return 1 << bits.Len(uint(size-1))
+}
+
+// parallel calls fn for every power-of-two-aligned [lo, hi) chunk of the
+// [0, n) range, each in its own goroutine, and waits for all calls to end.
+// A partial sum at index i only depends on partial sums in the same chunk,
+// unless i is the last index of a chunk.
+func parallel(n, size int, fn func(lo, hi int)) {
+	if n <= 0 {
+		return
+	}
+	if n <= size {
+		fn(0, n)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for lo := 0; lo < n; lo += size {
+		hi := lo + size
+		if n < hi {
+			hi = n
+		}
+
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			fn(lo, hi)
+		}(lo, hi)
+	}
+	wg.Wait()
+}
+
+// encodeChunk builds the partial sums of the [lo, hi) chunk of the tree from
+// the numbers stored there, leaving out contributions from other chunks.
+func (t Tree) encodeChunk(lo, hi int) {
+	for i := lo; 0 <= i && i < hi && i < len(t); i++ {
+		if j := i | (i + 1); j < hi && j < len(t) {
+			t[j] += t[i]
+		}
+	}
+}
+
+// decodeChunk is the inverse of encodeChunk.
+func (t Tree) decodeChunk(lo, hi int) {
+	// only odd indices hold partial sums that cover more than one number
+	for i := hi&^1 - 1; lo <= i && 0 < i && i < len(t); i -= 2 {
+		k := i & (i + 1)
+		if k < lo {
+			k = lo
+		}
+		for j := i; k < j && 0 < j && j < len(t); j &= j - 1 {
+			t[i] -= t[j-1]
+		}
+	}
+}
+
+// FromParallel creates a Binary Indexed Tree from a slice of numbers, as
+// From does, but splits the work over the given number of workers. If the
+// number of workers is not positive, it defaults to GOMAXPROCS. The tree is
+// built in power-of-two-aligned chunks, after which the partial sums that
+// span multiple chunks are completed.
+func FromParallel(numbers []int32, workers int, reUse ...bool) Tree {
+	var t Tree
+
+	if len(reUse) == 0 || !reUse[0] {
+		t = make(Tree, len(numbers))
+	} else {
+		t = numbers
+	}
+
+	size := chunkSize(len(t), workers)
+	parallel(len(t), size, func(lo, hi int) {
+		copy(t[lo:hi], numbers[lo:hi])
+		t.encodeChunk(lo, hi)
+	})
+
+	// add the last partial sum of every chunk to the one of its parent chunk
+	for i := size - 1; 0 <= i && i < len(t); i += size {
+		if j := i | (i + 1); 0 <= j && j < len(t) {
+			t[j] += t[i]
+		}
+	}
+
+	return t
+}
+
+// NumbersParallel returns all numbers in the tree, as Numbers does, but
+// splits the work over the given number of workers. If the number of
+// workers is not positive, it defaults to GOMAXPROCS.
+func (t Tree) NumbersParallel(numbers []int32, workers int) int {
+	n := len(t)
+	if len(numbers) < n {
+		n = len(numbers)
+	}
+	numbers = numbers[:n]
+
+	size := chunkSize(n, workers)
+	parallel(n, size, func(lo, hi int) {
+		copy(numbers[lo:hi], t[lo:hi])
+	})
+
+	// undo the partial sums that span multiple chunks, in reverse order
+	for i := (n/size)*size - 1; 0 <= i && i < n; i -= size {
+		if j := i | (i + 1); 0 <= j && j < n {
+			numbers[j] -= numbers[i]
+		}
+	}
+
+	parallel(n, size, func(lo, hi int) {
+		Tree(numbers).decodeChunk(lo, hi)
+	})
+
+	return n
+}
+
+// SumsParallel returns the prefix sums of the tree, as Sums does, but
+// splits the work over the given number of workers. If the number of
+// workers is not positive, it defaults to GOMAXPROCS.
+func (t Tree) SumsParallel(sums []int32, workers int) int {
+	n := len(t)
+	if len(sums) < n {
+		n = len(sums)
+	}
+
+	// the prefix sums just before and at the end of every chunk
+	size := chunkSize(n, workers)
+	offsets := make([]int32, (n+size-1)/size+1)
+	for k := range offsets {
+		offsets[k] = t.Sum(k*size - 1)
+	}
+
+	parallel(n, size, func(lo, hi int) {
+		copy(sums[lo:hi], t[lo:hi])
+
+		offset, total := offsets[lo/size], offsets[lo/size+1]
+		for i := lo; i < hi && i < len(sums); i++ {
+			switch j := i&(i+1) - 1; {
+			case lo <= j && j < i:
+				sums[i] += sums[j]
+			case i == lo+size-1:
+				sums[i] = total
+			default:
+				sums[i] += offset
+			}
+		}
+	})
+
+	for i := n; i < len(sums); i++ {
+		sums[i] = 0
+	}
+
+	return len(sums)
+}
+
+// ScaleParallel scales all numbers in the tree with the given factor, as
+// Scale does, but splits the work over the given number of workers. If the
+// number of workers is not positive, it defaults to GOMAXPROCS.
+func (t Tree) ScaleParallel(value int32, workers int) {
+	parallel(len(t), chunkSize(len(t), workers), func(lo, hi int) {
+		t[lo:hi].Scale(value)
+	})
+}
+
+// CopyParallel does a deep copy of the src tree, as Copy does, but splits
+// the work over the given number of workers. If the number of workers is
+// not positive, it defaults to GOMAXPROCS.
+func CopyParallel(dst, src Tree, workers int) int {
+	n := len(src)
+	if len(dst) < n {
+		n = len(dst)
+	}
+
+	parallel(len(dst), chunkSize(len(dst), workers), func(lo, hi int) {
+		if lo < n {
+			copy(dst[lo:hi], src[lo:])
+			lo = n
+		}
+		for i := lo; i < hi; i++ {
+			dst[i] = 0
+		}
+	})
+
+	if n == len(dst) {
+		return n
+	}
+
+	// Only the partial sums on the update path of the last copied index
+	// cover numbers of the src tree.
+	for i := n - 1; 0 <= i && i < len(dst); i |= i + 1 {
+		if n <= i {
+			dst[i] = src.RangeSum(i&(i+1), n)
+		}
+	}
+
+	return len(dst)
+}
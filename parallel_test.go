@@ -0,0 +1,181 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func parallelSamples() [][]int32 {
+	rand.Seed(18)
+
+	var samples [][]int32
+	for _, n := range []int{0, 1, 1000, 4 * minChunk, 5*minChunk + 123} {
+		numbers := make([]int32, n)
+		for i := range numbers {
+			numbers[i] = rand.Int31n(100) - 50
+		}
+		samples = append(samples, numbers)
+	}
+	return samples
+}
+
+func equalInt32s(t *testing.T, name string, got, want []int32) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("%s, length got: %d != want: %d\n", name, len(got), len(want))
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s, index: %d, got: %d != want: %d\n", name, i, got[i], want[i])
+			return
+		}
+	}
+}
+
+func TestFromParallel(t *testing.T) {
+	for _, numbers := range parallelSamples() {
+		want := From(numbers)
+		for _, workers := range []int{0, 1, 3, 8} {
+			equalInt32s(t, "FromParallel", FromParallel(numbers, workers), want)
+		}
+
+		reUse := make([]int32, len(numbers))
+		copy(reUse, numbers)
+		equalInt32s(t, "FromParallel(re-use)", FromParallel(reUse, 8, true), want)
+	}
+}
+
+func TestNumbersParallel(t *testing.T) {
+	for _, numbers := range parallelSamples() {
+		tree := From(numbers)
+
+		got := make([]int32, len(numbers)+1)
+		if n := tree.NumbersParallel(got, 8); n != len(numbers) {
+			t.Errorf("got: %d != want: %d\n", n, len(numbers))
+		}
+		equalInt32s(t, "NumbersParallel", got[:len(numbers)], numbers)
+
+		got = got[:len(numbers)/2]
+		tree.NumbersParallel(got, 8)
+		equalInt32s(t, "NumbersParallel(short)", got, numbers[:len(got)])
+
+		tree.NumbersParallel(tree, 8)
+		equalInt32s(t, "NumbersParallel(in place)", tree, numbers)
+	}
+}
+
+func TestSumsParallel(t *testing.T) {
+	for _, numbers := range parallelSamples() {
+		tree := From(numbers)
+
+		want := make([]int32, len(numbers)+5)
+		tree.Sums(want)
+
+		got := make([]int32, len(want))
+		for i := range got {
+			got[i] = -1
+		}
+		if n := tree.SumsParallel(got, 8); n != len(got) {
+			t.Errorf("got: %d != want: %d\n", n, len(got))
+		}
+		equalInt32s(t, "SumsParallel", got, want)
+
+		got = got[:len(numbers)/3]
+		tree.SumsParallel(got, 8)
+		equalInt32s(t, "SumsParallel(short)", got, want[:len(got)])
+
+		tree.SumsParallel(tree, 8)
+		equalInt32s(t, "SumsParallel(in place)", tree, want[:len(numbers)])
+	}
+}
+
+func TestScaleParallel(t *testing.T) {
+	for _, numbers := range parallelSamples() {
+		want, got := From(numbers), From(numbers)
+		want.Scale(-3)
+		got.ScaleParallel(-3, 8)
+		equalInt32s(t, "ScaleParallel", got, want)
+	}
+}
+
+func TestCopyParallel(t *testing.T) {
+	for _, numbers := range parallelSamples() {
+		src := From(numbers)
+
+		for _, l := range []int{0, len(src) / 2, len(src), len(src) + 1, 2*len(src) + 7} {
+			want, got := New(l), New(l)
+			for i := range got {
+				got[i] = -1
+			}
+
+			if n, m := CopyParallel(got, src, 8), Copy(want, src); n != m {
+				t.Errorf("length: %d, got: %d != want: %d\n", l, n, m)
+			}
+			equalInt32s(t, "CopyParallel", got, want)
+		}
+	}
+}
+
+func BenchmarkParallel(b *testing.B) {
+	const n = 1 << 22
+
+	numbers := make([]int32, n)
+	rand.Seed(18)
+	for i := range numbers {
+		numbers[i] = rand.Int31n(100)
+	}
+
+	b.Run("From", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			From(numbers)
+		}
+	})
+
+	b.Run("FromParallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			FromParallel(numbers, 0)
+		}
+	})
+
+	b.Run("Numbers", func(b *testing.B) {
+		tree, buf := From(numbers), make([]int32, n)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			tree.Numbers(buf)
+		}
+	})
+
+	b.Run("NumbersParallel", func(b *testing.B) {
+		tree, buf := From(numbers), make([]int32, n)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			tree.NumbersParallel(buf, 0)
+		}
+	})
+
+	b.Run("Sums", func(b *testing.B) {
+		tree, buf := From(numbers), make([]int32, n)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			tree.Sums(buf)
+		}
+	})
+
+	b.Run("SumsParallel", func(b *testing.B) {
+		tree, buf := From(numbers), make([]int32, n)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			tree.SumsParallel(buf, 0)
+		}
+	})
+}
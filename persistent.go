@@ -0,0 +1,177 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"slices"
+	"sort"
+)
+
+// entry is the value of a partial sum as of a given version.
+type entry struct {
+	version int
+	sum     int32
+}
+
+// PersistentTree is a Binary Indexed Tree that keeps its history. Every
+// update creates a new version of the tree, and prefix sums can be queried
+// as of any version that has not been released. Each partial sum is a fat
+// node: it keeps a list of its values, ordered by version, such that an
+// update takes O(log n) time and space. Queries on a past version take
+// O(log n · log u) time, for u updates of a partial sum.
+type PersistentTree struct {
+	nodes   [][]entry
+	version int // current version
+	oldest  int // oldest version that has not been released
+}
+
+// NewPersistentTree creates a PersistentTree of n elements, all set to zero.
+// The initial version of the tree is 0.
+func NewPersistentTree(n int) *PersistentTree {
+	if n < 0 {
+		n = 0
+	}
+	return &PersistentTree{nodes: make([][]entry, n)}
+}
+
+// PersistentTreeFrom creates a PersistentTree from a slice of numbers.
+// The initial version of the tree is 0.
+func PersistentTreeFrom(numbers []int32) *PersistentTree {
+	t := NewPersistentTree(len(numbers))
+	for i, sum := range From(numbers) {
+		if sum != 0 {
+			t.nodes[i] = []entry{{0, sum}}
+		}
+	}
+	return t
+}
+
+// Len returns the number of elements in the tree.
+func (t *PersistentTree) Len() int {
+	return len(t.nodes)
+}
+
+// Version returns the current version of the tree.
+func (t *PersistentTree) Version() int {
+	return t.version
+}
+
+// Oldest returns the oldest version of the tree that can still be queried.
+func (t *PersistentTree) Oldest() int {
+	return t.oldest
+}
+
+// at returns the partial sum at index i as of the given version.
+func (t *PersistentTree) at(version, i int) int32 {
+	h := t.nodes[i]
+	k := sort.Search(len(h), func(k int) bool { return version < h[k].version })
+	if k == 0 {
+		return 0
+	}
+	return h[k-1].sum
+}
+
+// Add adds the given value to the number at index i, and creates a new
+// version of the tree. If the index is outside of the tree, no value is
+// added and no version is created.
+func (t *PersistentTree) Add(i int, value int32) {
+	if i < 0 || len(t.nodes) <= i {
+		return
+	}
+
+	t.version++
+	for ; i < len(t.nodes); i |= i + 1 {
+		h := t.nodes[i]
+		var sum int32
+		if len(h) != 0 {
+			sum = h[len(h)-1].sum
+		}
+		t.nodes[i] = append(h, entry{t.version, sum + value})
+	}
+}
+
+// Set sets a number at a given index, and creates a new version of the
+// tree. If the index is outside of the tree, no updates are made.
+func (t *PersistentTree) Set(i int, number int32) {
+	t.Add(i, number-t.NumberAt(t.version, i))
+}
+
+// Sum returns the prefix sum at index i of the current version.
+func (t *PersistentTree) Sum(i int) int32 {
+	return t.SumAt(t.version, i)
+}
+
+// RangeSum returns the sum of the [lo, hi) range of the current version.
+func (t *PersistentTree) RangeSum(lo, hi int) int32 {
+	return t.RangeSumAt(t.version, lo, hi)
+}
+
+// SumAt returns the prefix sum at index i as of the given version. If i is
+// larger than the largest index, the prefix sum of the largest index is
+// returned. If the version has been released, or does not exist yet, 0 is
+// returned.
+func (t *PersistentTree) SumAt(version, i int) int32 {
+	if version < t.oldest || t.version < version {
+		return 0
+	}
+	if len(t.nodes) <= i {
+		i = len(t.nodes) - 1
+	}
+
+	var sum int32
+	for 0 <= i {
+		sum += t.at(version, i)
+		i = i&(i+1) - 1
+	}
+	return sum
+}
+
+// RangeSumAt returns the sum of the [lo, hi) range as of the given version.
+// In case of a partial overlap of the range with the tree, RangeSumAt will
+// return the sum of the intersection of the given interval with the tree.
+func (t *PersistentTree) RangeSumAt(version, lo, hi int) int32 {
+	if hi <= lo {
+		return 0
+	}
+	return t.SumAt(version, hi-1) - t.SumAt(version, lo-1)
+}
+
+// NumberAt returns the element at index i as of the given version. If i is
+// outside of the tree, or the version cannot be queried, 0 is returned.
+func (t *PersistentTree) NumberAt(version, i int) int32 {
+	if i < 0 || len(t.nodes) <= i || version < t.oldest || t.version < version {
+		return 0
+	}
+
+	number := t.at(version, i)
+	for j := i; i&(i+1) < j; j &= j - 1 {
+		number -= t.at(version, j-1)
+	}
+	return number
+}
+
+// Release releases all versions older than the given version, which can no
+// longer be queried afterwards. The history that is only needed by those
+// versions is freed. Release takes O(n + h) time, for h entries of history.
+func (t *PersistentTree) Release(version int) {
+	if version <= t.oldest {
+		return
+	}
+	if t.version < version {
+		version = t.version
+	}
+	t.oldest = version
+
+	for i, h := range t.nodes {
+		// keep the last value as of the oldest version, unless it is zero
+		k := sort.Search(len(h), func(k int) bool { return version < h[k].version })
+		if 0 < k && h[k-1].sum != 0 {
+			k--
+		}
+		if 0 < k {
+			t.nodes[i] = slices.Clone(h[k:])
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func checkVersion(t *testing.T, tree *PersistentTree, version int, want []int32) {
+	t.Helper()
+
+	wtree := From(want)
+	for i := -1; i <= len(want); i++ {
+		if got := tree.SumAt(version, i); got != wtree.Sum(i) {
+			t.Fatalf("version: %d, index: %d, sum got: %d != want: %d\n", version, i, got, wtree.Sum(i))
+		}
+		if got := tree.NumberAt(version, i); i >= 0 && i < len(want) && got != want[i] {
+			t.Fatalf("version: %d, index: %d, number got: %d != want: %d\n", version, i, got, want[i])
+		}
+		if got := tree.RangeSumAt(version, i, len(want)); 0 <= i && got != wtree.RangeSum(i, len(want)) {
+			t.Fatalf("version: %d, index: %d, range sum got: %d != want: %d\n", version, i, got, wtree.RangeSum(i, len(want)))
+		}
+	}
+}
+
+func TestPersistentTree(t *testing.T) {
+	empty := NewPersistentTree(0)
+	empty.Add(0, 1)
+	empty.Set(0, 1)
+	if empty.Version() != 0 || empty.Sum(0) != 0 || empty.RangeSum(0, 1) != 0 {
+		t.Errorf("empty tree got version: %d\n", empty.Version())
+	}
+
+	rand.Seed(18)
+	const n = 37
+	numbers := make([]int32, n)
+	for i := range numbers {
+		numbers[i] = rand.Int31n(10)
+	}
+
+	tree := PersistentTreeFrom(numbers)
+	history := [][]int32{append([]int32(nil), numbers...)}
+	for k := 0; k < 200; k++ {
+		i, num := rand.Intn(n), rand.Int31n(10)
+		if k%2 == 0 {
+			tree.Set(i, num)
+			numbers[i] = num
+		} else {
+			tree.Add(i, num)
+			numbers[i] += num
+		}
+		history = append(history, append([]int32(nil), numbers...))
+	}
+
+	if tree.Len() != n || tree.Version() != len(history)-1 {
+		t.Fatalf("length got: %d, version got: %d\n", tree.Len(), tree.Version())
+	}
+	for v, want := range history {
+		checkVersion(t, tree, v, want)
+	}
+
+	var before int
+	for _, h := range tree.nodes {
+		before += len(h)
+	}
+
+	tree.Release(150)
+	if tree.Oldest() != 150 {
+		t.Errorf("oldest got: %d != want: 150\n", tree.Oldest())
+	}
+	if got := tree.SumAt(149, n); got != 0 {
+		t.Errorf("released version got: %d != want: 0\n", got)
+	}
+	if got := tree.SumAt(tree.Version()+1, n); got != 0 {
+		t.Errorf("future version got: %d != want: 0\n", got)
+	}
+	for v := 150; v < len(history); v++ {
+		checkVersion(t, tree, v, history[v])
+	}
+
+	var after int
+	for _, h := range tree.nodes {
+		after += len(h)
+	}
+	if before <= after {
+		t.Errorf("history entries before: %d, after: %d\n", before, after)
+	}
+
+	tree.Release(tree.Version() + 10)
+	checkVersion(t, tree, tree.Version(), numbers)
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+// A tree of non-negative counts can be seen as a multiset, or a sequence of
+// units, where index i holds as many units as the count at index i. The
+// units are numbered from zero, in index order. Rank and Select are each
+// other's inverse: Select(k) is the index that holds unit k, while Rank(i)
+// is the number of the first unit at index i, if any. Indices with a zero
+// count hold no units, and are never returned by Select.
+
+// Rank returns the number of units at the indices before index i, i.e. the
+// sum of the [0, i) range. If i is not positive, Rank returns zero. If i is
+// larger than the length of the tree, the total number of units is returned.
+// This operation assumes the counts in the tree to be non-negative.
+func (t Tree) Rank(i int) int32 {
+	if i <= 0 {
+		return 0
+	}
+	return t.Sum(i - 1)
+}
+
+// Select returns the index that holds unit k, i.e. the smallest index i
+// for which Rank(i+1) > k. If k is negative, or not smaller than the total
+// number of units, the length of the tree is returned. This operation
+// assumes the counts in the tree to be non-negative.
+func (t Tree) Select(k int32) int {
+	if k < 0 {
+		return len(t)
+	}
+
+	i, _ := t.SearchSumGT(k)
+	return i
+}
+
+// SelectRange returns the index that holds unit k of the units in the
+// [lo, hi) range, where the units in the range are numbered from zero. The
+// range is intersected with the index range of the tree. If k is negative,
+// or not smaller than the number of units in the range, hi is returned.
+// This operation assumes the counts in the tree to be non-negative.
+func (t Tree) SelectRange(k int32, lo, hi int) int {
+	l, h := t.clip(lo, hi)
+	if k < 0 || h <= l || t.RangeSum(l, h) <= k {
+		return hi
+	}
+
+	i, _ := t.SearchRangeSum(l, k+1)
+	return i - 1
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import "testing"
+
+func TestRankSelect(t *testing.T) {
+	counts := []int32{0, 2, 0, 0, 1, 3, 0, 1, 0, 0, 0, 4, 0}
+
+	// units holds, for every unit, the index that holds it
+	var units []int
+	for i, c := range counts {
+		for ; 0 < c; c-- {
+			units = append(units, i)
+		}
+	}
+
+	tree := From(counts)
+	for i := -1; i <= len(counts)+1; i++ {
+		var want int32
+		for _, u := range units {
+			if u < i {
+				want++
+			}
+		}
+		if got := tree.Rank(i); got != want {
+			t.Errorf("Rank(%d) got: %d != want: %d\n", i, got, want)
+		}
+	}
+
+	for k := int32(-1); k <= int32(len(units))+1; k++ {
+		want := len(counts)
+		if 0 <= k && int(k) < len(units) {
+			want = units[k]
+		}
+		if got := tree.Select(k); got != want {
+			t.Errorf("Select(%d) got: %d != want: %d\n", k, got, want)
+		}
+		if got := tree.Select(k); want < len(counts) && tree.Rank(got) > k {
+			t.Errorf("Rank(Select(%d)) got: %d > %d\n", k, tree.Rank(got), k)
+		}
+	}
+
+	for lo := -1; lo <= len(counts)+1; lo++ {
+		for hi := lo - 1; hi <= len(counts)+1; hi++ {
+			var inRange []int
+			for _, u := range units {
+				if lo <= u && u < hi {
+					inRange = append(inRange, u)
+				}
+			}
+
+			for k := int32(-1); k <= int32(len(inRange)); k++ {
+				want := hi
+				if 0 <= k && int(k) < len(inRange) {
+					want = inRange[k]
+				}
+				if got := tree.SelectRange(k, lo, hi); got != want {
+					t.Errorf("SelectRange(%d, %d, %d) got: %d != want: %d\n", k, lo, hi, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+// Ring is a Binary Indexed Tree of fixed capacity that holds a sliding window
+// over the most recently pushed numbers. Once the ring is full, every push
+// overwrites the oldest number. All indices are in window order: index 0 is
+// the oldest number in the window and index Len()-1 the most recent one.
+type Ring struct {
+	tree  Tree
+	head  int // slot of the next push
+	count int // number of numbers in the window
+}
+
+// NewRing creates an empty Ring with a capacity of n numbers.
+func NewRing(n int) *Ring {
+	return &Ring{tree: New(n)}
+}
+
+// Len returns the number of numbers in the window.
+func (r *Ring) Len() int {
+	return r.count
+}
+
+// Cap returns the capacity of the ring.
+func (r *Ring) Cap() int {
+	return len(r.tree)
+}
+
+// slot returns the slot in the tree of the number at index i of the window.
+// Index i must be in the [0, Len()] range.
+func (r *Ring) slot(i int) int {
+	s := r.head - r.count + i
+	if s < 0 {
+		s += len(r.tree)
+	}
+	return s
+}
+
+// Push adds a number to the window. If the ring is full, the oldest
+// number is dropped from the window. Push takes O(log n) time.
+func (r *Ring) Push(number int32) {
+	if len(r.tree) == 0 {
+		return
+	}
+
+	r.tree.Set(r.head, number)
+	if r.head++; r.head == len(r.tree) {
+		r.head = 0
+	}
+	if r.count < len(r.tree) {
+		r.count++
+	}
+}
+
+// Number returns the number at index i of the window.
+// If i is outside of the window, 0 will be returned.
+func (r *Ring) Number(i int) int32 {
+	if i < 0 || r.count <= i {
+		return 0
+	}
+	return r.tree.Number(r.slot(i))
+}
+
+// RangeSum returns the sum of the [lo, hi) range of the window. In case of a
+// partial overlap of the range with the window, RangeSum will return the sum
+// of the intersection of the given interval with the window.
+func (r *Ring) RangeSum(lo, hi int) int32 {
+	if lo < 0 {
+		lo = 0
+	}
+	if r.count < hi {
+		hi = r.count
+	}
+	if hi <= lo {
+		return 0
+	}
+
+	lo, hi = r.slot(lo), r.slot(hi)
+	if lo < hi {
+		return r.tree.RangeSum(lo, hi)
+	}
+	return r.tree.RangeSum(lo, len(r.tree)) + r.tree.RangeSum(0, hi)
+}
+
+// Sum returns the prefix sum at index i of the window. If i is larger than
+// the largest index, the sum of all numbers in the window is returned.
+func (r *Ring) Sum(i int) int32 {
+	return r.RangeSum(0, i+1)
+}
+
+// WindowSum returns the sum of the k most recent numbers in the window.
+// If k is larger than the length of the window, all numbers are added.
+func (r *Ring) WindowSum(k int) int32 {
+	return r.RangeSum(r.count-k, r.count)
+}
+
+// SearchSum returns the largest index of the window and corresponding prefix
+// sum that is smaller than or equal to the given value. In case no prefix
+// sum is small enough, -1 is returned. This operation assumes the numbers
+// to be non-negative.
+func (r *Ring) SearchSum(value int32) (int, int32) {
+	if r.count == 0 || value < 0 {
+		return -1, 0
+	}
+
+	// The window starts at slot lo and runs up to the end of the tree. When
+	// the window wraps around, it continues at slot 0 up to slot lo.
+	lo := r.slot(0)
+	sum := r.tree.RangeSum(lo, len(r.tree))
+	if value < sum {
+		hi, hsum := r.tree.SearchRangeSum(lo, value+1)
+		return hi - lo - 2, hsum - r.tree.Number(hi-1)
+	}
+	if lo == 0 {
+		return r.count - 1, sum
+	}
+
+	i, isum := r.tree.SearchSum(value - sum)
+	if lo <= i {
+		i, isum = lo-1, r.tree.Sum(lo-1)
+	}
+	return len(r.tree) - lo + i, sum + isum
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	empty := NewRing(0)
+	empty.Push(1)
+	if empty.Len() != 0 || empty.Cap() != 0 || empty.WindowSum(1) != 0 {
+		t.Errorf("empty ring got length: %d\n", empty.Len())
+	}
+
+	rand.Seed(18)
+	for _, n := range []int{1, 5, 8, 13} {
+		r := NewRing(n)
+
+		var pushed []int32
+		for k := 0; k < 3*n+2; k++ {
+			num := rand.Int31n(5)
+			r.Push(num)
+			pushed = append(pushed, num)
+
+			window := pushed
+			if n < len(window) {
+				window = window[len(window)-n:]
+			}
+			if r.Len() != len(window) || r.Cap() != n {
+				t.Fatalf("n: %d, length got: %d != want: %d\n", n, r.Len(), len(window))
+			}
+
+			for i := -1; i <= len(window); i++ {
+				var want int32
+				if 0 <= i && i < len(window) {
+					want = window[i]
+				}
+				if got := r.Number(i); got != want {
+					t.Errorf("n: %d, k: %d, Number(%d) got: %d != want: %d\n", n, k, i, got, want)
+				}
+			}
+
+			for lo := -1; lo <= len(window)+1; lo++ {
+				for hi := lo - 1; hi <= len(window)+1; hi++ {
+					var want int32
+					for i, num := range window {
+						if lo <= i && i < hi {
+							want += num
+						}
+					}
+					if got := r.RangeSum(lo, hi); got != want {
+						t.Errorf("n: %d, k: %d, RangeSum(%d, %d) got: %d != want: %d\n", n, k, lo, hi, got, want)
+					}
+				}
+			}
+
+			for j := 0; j <= len(window)+1; j++ {
+				var want int32
+				for i := len(window) - j; i < len(window); i++ {
+					if 0 <= i {
+						want += window[i]
+					}
+				}
+				if got := r.WindowSum(j); got != want {
+					t.Errorf("n: %d, k: %d, WindowSum(%d) got: %d != want: %d\n", n, k, j, got, want)
+				}
+			}
+
+			total := r.Sum(len(window))
+			for value := int32(-1); value <= total+1; value++ {
+				want, wsum := -1, int32(0)
+				var sum int32
+				for i, num := range window {
+					if sum += num; sum <= value {
+						want, wsum = i, sum
+					}
+				}
+				if got, gsum := r.SearchSum(value); got != want || gsum != wsum {
+					t.Errorf(
+						"n: %d, k: %d, SearchSum(%d) got: (%d, %d) != want: (%d, %d)\n",
+						n, k, value, got, gsum, want, wsum,
+					)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,398 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+// maxBlock is the largest number of elements in a block of a Sequence. A
+// block that grows larger is split in two. A block that shrinks below a
+// quarter of maxBlock is merged with a neighbor, or when the result does
+// not fit, the numbers of both blocks are spread evenly.
+const maxBlock = 1 << 10
+
+// Sequence is a sequence of numbers that supports prefix sums, like Tree,
+// but where numbers can also be inserted and deleted at any index. The
+// numbers are stored in small trees, or blocks, which are kept in order in
+// a treap: a binary search tree on the block positions, balanced by random
+// priorities. Every node of the treap holds the number of blocks, numbers
+// and their sum in its subtree. Queries and updates take O(log n + B) time,
+// for blocks of at most B numbers. Splitting or merging a block takes
+// O(log n + B) expected time as well, such that inserts and deletes take
+// O(log n) amortized time for the fixed B of maxBlock.
+type Sequence struct {
+	root *node
+	seed uint32 // priority generator state
+}
+
+// node is a node of the treap of a Sequence, which holds a single block.
+type node struct {
+	blk         Tree
+	left, right *node
+	prio        uint32
+	bsum        int32 // sum of the numbers in blk
+	blocks      int   // number of blocks in the subtree
+	size        int   // number of numbers in the subtree
+	sum         int32 // sum of the numbers in the subtree
+}
+
+// count returns the number of blocks in the subtree of n.
+func (n *node) count() int {
+	if n == nil {
+		return 0
+	}
+	return n.blocks
+}
+
+// len returns the number of numbers in the subtree of n.
+func (n *node) len() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// total returns the sum of the numbers in the subtree of n.
+func (n *node) total() int32 {
+	if n == nil {
+		return 0
+	}
+	return n.sum
+}
+
+// update recomputes the subtree totals of n from its children.
+func (n *node) update() *node {
+	n.blocks = n.left.count() + 1 + n.right.count()
+	n.size = n.left.len() + len(n.blk) + n.right.len()
+	n.sum = n.left.total() + n.bsum + n.right.total()
+	return n
+}
+
+// each calls fn for the blocks in the subtree of n, in order, for as long
+// as fn returns true. It reports whether all blocks were visited.
+func (n *node) each(fn func(blk Tree) bool) bool {
+	return n == nil || n.left.each(fn) && fn(n.blk) && n.right.each(fn)
+}
+
+// splitTreap splits the treap n in a treap with its first k blocks,
+// and a treap with the remaining blocks.
+func splitTreap(n *node, k int) (*node, *node) {
+	if n == nil {
+		return nil, nil
+	}
+
+	lb := n.left.count()
+	if k <= lb {
+		l, r := splitTreap(n.left, k)
+		n.left = r
+		return l, n.update()
+	}
+
+	l, r := splitTreap(n.right, k-lb-1)
+	n.right = l
+	return n.update(), r
+}
+
+// joinTreap joins the treaps a and b, with the blocks of a first.
+func joinTreap(a, b *node) *node {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case b.prio < a.prio:
+		a.right = joinTreap(a.right, b)
+		return a.update()
+	default:
+		b.left = joinTreap(a, b.left)
+		return b.update()
+	}
+}
+
+// NewSequence creates an empty Sequence.
+func NewSequence() *Sequence {
+	return &Sequence{}
+}
+
+// SequenceFrom creates a Sequence from a slice of numbers.
+func SequenceFrom(numbers []int32) *Sequence {
+	s := &Sequence{}
+	for lo := 0; lo < len(numbers); lo += maxBlock / 2 {
+		hi := lo + maxBlock/2
+		if len(numbers) < hi {
+			hi = len(numbers)
+		}
+		s.root = joinTreap(s.root, s.newNode(From(numbers[lo:hi])))
+	}
+	return s
+}
+
+// newNode creates a treap node for the block, with a pseudo-random priority.
+func (s *Sequence) newNode(blk Tree) *node {
+	s.seed += 0x9e3779b9
+	p := s.seed
+	p ^= p >> 16
+	p *= 0x85ebca6b
+	p ^= p >> 13
+	p *= 0xc2b2ae35
+	p ^= p >> 16
+
+	n := &node{blk: blk, prio: p, bsum: blk.Sum(len(blk))}
+	return n.update()
+}
+
+// locate returns the node of the block that holds the number at index i,
+// the position of that block, the index of the number within the block,
+// and the sum of the numbers before the block. Index i must be within the
+// sequence.
+func (s *Sequence) locate(i int) (*node, int, int, int32) {
+	n, b := s.root, 0
+	var before int32
+	for {
+		if l := n.left.len(); i < l {
+			n = n.left
+			continue
+		}
+
+		i -= n.left.len()
+		b += n.left.count()
+		before += n.left.total()
+		if i < len(n.blk) {
+			return n, b, i, before
+		}
+
+		i -= len(n.blk)
+		b++
+		before += n.bsum
+		n = n.right
+	}
+}
+
+// adjust adds size and sum to the totals of block b, and of all
+// subtrees that hold it.
+func (s *Sequence) adjust(b, size int, sum int32) {
+	for n := s.root; ; {
+		n.size += size
+		n.sum += sum
+
+		switch lb := n.left.count(); {
+		case b < lb:
+			n = n.left
+		case b == lb:
+			n.bsum += sum
+			return
+		default:
+			b -= lb + 1
+			n = n.right
+		}
+	}
+}
+
+// Len returns the number of elements in the sequence.
+func (s *Sequence) Len() int {
+	return s.root.len()
+}
+
+// Sum returns the prefix sum at index i of the sequence. If i is larger than
+// the largest index, the prefix sum of the largest index is returned.
+func (s *Sequence) Sum(i int) int32 {
+	if n := s.Len(); n <= i {
+		i = n - 1
+	}
+	if i < 0 {
+		return 0
+	}
+
+	n, _, j, before := s.locate(i)
+	return before + n.blk.Sum(j)
+}
+
+// RangeSum returns the sum of the [lo, hi) range. In case of a partial
+// overlap of the range with the sequence, RangeSum will return the sum
+// of the intersection of the given interval with the sequence.
+func (s *Sequence) RangeSum(lo, hi int) int32 {
+	if hi <= lo {
+		return 0
+	}
+	return s.Sum(hi-1) - s.Sum(lo-1)
+}
+
+// Number returns the element at index i.
+// If i is outside of the sequence, 0 will be returned.
+func (s *Sequence) Number(i int) int32 {
+	if i < 0 || s.Len() <= i {
+		return 0
+	}
+
+	n, _, j, _ := s.locate(i)
+	return n.blk.Number(j)
+}
+
+// Numbers returns all numbers in the sequence. If the numbers slice is
+// too short, only numbers up to the length of the slice will be returned.
+func (s *Sequence) Numbers(numbers []int32) int {
+	var n int
+	s.root.each(func(blk Tree) bool {
+		n += blk.Numbers(numbers[n:])
+		return n < len(numbers)
+	})
+	return n
+}
+
+// Set sets a number at a given index. If the index
+// is outside of the sequence, no updates are made.
+func (s *Sequence) Set(i int, number int32) {
+	if i < 0 || s.Len() <= i {
+		return
+	}
+
+	n, b, j, _ := s.locate(i)
+	s.adjust(b, 0, number-n.blk.Number(j))
+	n.blk.Set(j, number)
+}
+
+// Insert inserts a number at index i, shifting the numbers at index i and
+// beyond one index up. Index i can be equal to the length of the sequence,
+// to append the number. If the index is outside of that range, no updates
+// are made.
+func (s *Sequence) Insert(i int, number int32) {
+	length := s.Len()
+	if i < 0 || length < i {
+		return
+	}
+
+	if s.root == nil {
+		s.root = s.newNode(Tree{number})
+		return
+	}
+
+	// append to the last block when i is the length of the sequence
+	var n *node
+	var b, j int
+	if i < length {
+		n, b, j, _ = s.locate(i)
+	} else {
+		n, b, j, _ = s.locate(i - 1)
+		j++
+	}
+
+	blk := n.blk
+	blk.decode(j)
+	blk = append(blk, 0)
+	copy(blk[j+1:], blk[j:])
+	blk[j] = number
+	blk.encode(j)
+	n.blk = blk
+	s.adjust(b, 1, number)
+
+	if maxBlock < len(blk) {
+		s.respread(b, 1)
+	}
+}
+
+// Delete removes the number at index i and returns it, shifting the numbers
+// beyond index i one index down. If the index is outside of the sequence,
+// no updates are made and 0 is returned.
+func (s *Sequence) Delete(i int) int32 {
+	if i < 0 || s.Len() <= i {
+		return 0
+	}
+
+	n, b, j, _ := s.locate(i)
+	blk := n.blk
+	blk.decode(j)
+	number := blk[j]
+	copy(blk[j:], blk[j+1:])
+	blk = blk[:len(blk)-1]
+	blk.encode(j)
+	n.blk = blk
+	s.adjust(b, -1, -number)
+
+	if len(blk) < maxBlock/4 {
+		s.merge(b)
+	}
+	return number
+}
+
+// merge removes block b when it is empty, or joins it with its smallest
+// neighbor otherwise.
+func (s *Sequence) merge(b int) {
+	switch last := s.root.count() - 1; {
+	case len(s.block(b).blk) == 0:
+		s.respread(b, 1)
+	case 0 < b && (b == last || len(s.block(b-1).blk) < len(s.block(b+1).blk)):
+		s.respread(b-1, 2)
+	case b < last:
+		s.respread(b, 2)
+	}
+}
+
+// block returns the node of block b, which must be within the sequence.
+func (s *Sequence) block(b int) *node {
+	n := s.root
+	for {
+		switch lb := n.left.count(); {
+		case b < lb:
+			n = n.left
+		case b == lb:
+			return n
+		default:
+			b -= lb + 1
+			n = n.right
+		}
+	}
+}
+
+// respread replaces the k blocks from block b onwards by blocks that hold
+// the same numbers: none when they are empty, a single block when the
+// numbers fit, and two blocks of equal size otherwise. Taking the blocks
+// out of the treap and putting new ones back takes O(log n) expected time.
+func (s *Sequence) respread(b, k int) {
+	l, r := splitTreap(s.root, b)
+	m, r := splitTreap(r, k)
+
+	numbers := make([]int32, m.len())
+	var n int
+	m.each(func(blk Tree) bool {
+		n += blk.Numbers(numbers[n:])
+		return true
+	})
+
+	switch h := len(numbers) / 2; {
+	case len(numbers) == 0:
+		m = nil
+	case len(numbers) <= maxBlock:
+		m = s.newNode(From(numbers, true))
+	default:
+		lo, hi := From(numbers[:h:h], true), From(numbers[h:], true)
+		m = joinTreap(s.newNode(lo), s.newNode(hi))
+	}
+	s.root = joinTreap(joinTreap(l, m), r)
+}
+
+// SearchSum returns the largest index and corresponding prefix sum that is
+// smaller than or equal to the given value. In case no prefix sum is small
+// enough, -1 is returned. This operation assumes the prefix sums to increase
+// monotonically.
+func (s *Sequence) SearchSum(value int32) (int, int32) {
+	// descend to the first block with a prefix sum larger than the value
+	var i int
+	var sum int32
+	for n := s.root; n != nil; {
+		if value < sum+n.left.total() {
+			n = n.left
+			continue
+		}
+
+		i += n.left.len()
+		sum += n.left.total()
+		if value < sum+n.bsum {
+			j, bsum := n.blk.SearchSum(value - sum)
+			return i + j, sum + bsum
+		}
+
+		i += len(n.blk)
+		sum += n.bsum
+		n = n.right
+	}
+	return i - 1, sum
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// checkTreap verifies the priorities and subtree totals of the treap of
+// blocks, and returns its depth.
+func checkTreap(t *testing.T, n *node) int {
+	t.Helper()
+
+	if n == nil {
+		return 0
+	}
+	for _, c := range []*node{n.left, n.right} {
+		if c != nil && n.prio < c.prio {
+			t.Fatalf("child priority: %d > parent priority: %d\n", c.prio, n.prio)
+		}
+	}
+	if n.bsum != n.blk.Sum(len(n.blk)) || len(n.blk) == 0 || maxBlock < len(n.blk) {
+		t.Fatalf("block of length: %d, sum got: %d\n", len(n.blk), n.bsum)
+	}
+
+	ld, rd := checkTreap(t, n.left), checkTreap(t, n.right)
+	blocks, size, sum := n.blocks, n.size, n.sum
+	if n.update(); blocks != n.blocks || size != n.size || sum != n.sum {
+		t.Fatalf("totals got: (%d, %d, %d) != want: (%d, %d, %d)\n",
+			blocks, size, sum, n.blocks, n.size, n.sum)
+	}
+	return 1 + max(ld, rd)
+}
+
+func checkSequence(t *testing.T, s *Sequence, want []int32) {
+	t.Helper()
+
+	checkTreap(t, s.root)
+
+	if s.Len() != len(want) {
+		t.Fatalf("length got: %d != want: %d\n", s.Len(), len(want))
+	}
+
+	numbers := make([]int32, len(want)+1)
+	if n := s.Numbers(numbers); n != len(want) {
+		t.Errorf("Numbers got: %d != want: %d\n", n, len(want))
+	}
+
+	var sum int32
+	for i, num := range want {
+		sum += num
+		if numbers[i] != num {
+			t.Fatalf("index: %d, number got: %d != want: %d\n", i, numbers[i], num)
+		}
+		if got := s.Sum(i); got != sum {
+			t.Fatalf("index: %d, sum got: %d != want: %d\n", i, got, sum)
+		}
+		if got := s.Number(i); i%61 == 0 && got != num {
+			t.Fatalf("index: %d, number got: %d != want: %d\n", i, got, num)
+		}
+	}
+}
+
+func TestSequence(t *testing.T) {
+	s := NewSequence()
+	s.Delete(0)
+	s.Insert(1, 5)
+	s.Set(0, 5)
+	if i, sum := s.SearchSum(5); i != -1 || sum != 0 || s.Sum(0) != 0 || s.Number(0) != 0 {
+		t.Errorf("empty sequence got: (%d, %d)\n", i, sum)
+	}
+
+	rand.Seed(18)
+	var want []int32
+	for k := 0; k < 3*maxBlock; k++ {
+		i, num := rand.Intn(len(want)+1), rand.Int31n(10)
+		s.Insert(i, num)
+		want = append(want, 0)
+		copy(want[i+1:], want[i:])
+		want[i] = num
+	}
+	checkSequence(t, s, want)
+	checkSequence(t, SequenceFrom(want), want)
+
+	for k := 0; k < 4*maxBlock; k++ {
+		i := rand.Intn(len(want) + 1)
+		switch k % 4 {
+		case 0, 1:
+			num := rand.Int31n(10)
+			s.Insert(i, num)
+			want = append(want, 0)
+			copy(want[i+1:], want[i:])
+			want[i] = num
+		case 2:
+			var num int32
+			if i < len(want) {
+				num = want[i]
+				want = append(want[:i], want[i+1:]...)
+			}
+			if got := s.Delete(i); got != num {
+				t.Errorf("Delete(%d) got: %d != want: %d\n", i, got, num)
+			}
+		case 3:
+			num := rand.Int31n(10)
+			s.Set(i, num)
+			if i < len(want) {
+				want[i] = num
+			}
+		}
+
+		if k%97 == 0 {
+			checkSequence(t, s, want)
+		}
+	}
+	checkSequence(t, s, want)
+
+	sums := make([]int32, len(want))
+	From(want).Sums(sums)
+	for i := -1; i <= len(want); i++ {
+		var wsum int32
+		for j := 0; j < i && j < len(want); j++ {
+			wsum += want[j]
+		}
+		if got := s.RangeSum(0, i); got != wsum {
+			t.Errorf("RangeSum(0, %d) got: %d != want: %d\n", i, got, wsum)
+		}
+	}
+	for _, value := range []int32{-1, 0, 1, sums[len(sums)/3], sums[len(sums)-1], sums[len(sums)-1] + 1} {
+		want, wsum := From(want).SearchSum(value)
+		if got, gsum := s.SearchSum(value); got != want || gsum != wsum {
+			t.Errorf("SearchSum(%d) got: (%d, %d) != want: (%d, %d)\n", value, got, gsum, want, wsum)
+		}
+	}
+
+	for s.Len() > 0 {
+		i := rand.Intn(s.Len())
+		if got := s.Delete(i); got != want[i] {
+			t.Fatalf("Delete(%d) got: %d != want: %d\n", i, got, want[i])
+		}
+		want = append(want[:i], want[i+1:]...)
+	}
+	checkSequence(t, s, want)
+}
+
+func TestSequenceBalance(t *testing.T) {
+	// grow a sequence at its front, which splits the first block over and
+	// over again, and shrink it again from the back
+	rand.Seed(18)
+	s := SequenceFrom(make([]int32, 8*maxBlock))
+	want := make([]int32, 16*maxBlock, 24*maxBlock)
+	for k := len(want) - 1; 0 <= k; k-- {
+		want[k] = rand.Int31n(10)
+		s.Insert(0, want[k])
+	}
+	want = want[:cap(want)]
+	checkSequence(t, s, want)
+
+	// the expected depth of a treap is about 3 ln m, for m blocks
+	if depth, blocks := checkTreap(t, s.root), s.root.count(); 32 < depth || blocks < 40 {
+		t.Errorf("depth: %d, blocks: %d\n", depth, blocks)
+	}
+
+	for s.Len() > maxBlock {
+		s.Delete(s.Len() - 1)
+		want = want[:len(want)-1]
+	}
+	checkSequence(t, s, want)
+}
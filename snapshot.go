@@ -0,0 +1,239 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// A page of a PagedTree holds pageSize = 1<<pageShift partial sums. Pages
+// are the unit of copy-on-write: the first update of a page after a
+// snapshot copies that page, and only that page.
+const (
+	pageShift = 9
+	pageSize  = 1 << pageShift
+)
+
+// page is a fixed-size block of partial sums. The epoch records the
+// snapshot generation in which the page was created. A page of an earlier
+// epoch may be shared with a snapshot, and must be copied before an update.
+type page struct {
+	epoch uint64
+	sums  [pageSize]int32
+}
+
+// Snapshot is an immutable, read-only view of a PagedTree, as it was at the
+// time the snapshot was taken. Updates to the tree after that time are not
+// visible in the snapshot. A snapshot can be read concurrently with updates
+// to its tree, and from multiple goroutines.
+type Snapshot struct {
+	pages []*page
+	n     int
+}
+
+// at returns the partial sum at index i, which must be within the view.
+func (s *Snapshot) at(i int) int32 {
+	return s.pages[i>>pageShift].sums[i&(pageSize-1)]
+}
+
+// Len returns the number of elements in the snapshot.
+func (s *Snapshot) Len() int {
+	return s.n
+}
+
+// Sum returns the prefix sum at index i of the snapshot. If i is larger than
+// the largest index, the prefix sum of the largest index is returned.
+func (s *Snapshot) Sum(i int) int32 {
+	if s.n <= i {
+		i = s.n - 1
+	}
+
+	var sum int32
+	for 0 <= i {
+		sum += s.at(i)
+		i = i&(i+1) - 1
+	}
+	return sum
+}
+
+// RangeSum returns the sum of the [lo, hi) range. In case of a partial
+// overlap of the range with the snapshot, RangeSum will return the sum
+// of the intersection of the given interval with the snapshot.
+func (s *Snapshot) RangeSum(lo, hi int) int32 {
+	if hi <= lo {
+		return 0
+	}
+	return s.Sum(hi-1) - s.Sum(lo-1)
+}
+
+// Number returns the element at index i.
+// If i is outside of the snapshot, 0 will be returned.
+func (s *Snapshot) Number(i int) int32 {
+	if i < 0 || s.n <= i {
+		return 0
+	}
+
+	number := s.at(i)
+	for j := i; i&(i+1) < j; j &= j - 1 {
+		number -= s.at(j - 1)
+	}
+	return number
+}
+
+// Numbers returns all numbers in the snapshot. If the numbers slice is
+// too short, only numbers up to the length of the slice will be returned.
+func (s *Snapshot) Numbers(numbers []int32) int {
+	if s.n < len(numbers) {
+		numbers = numbers[:s.n]
+	}
+
+	// the leading partial sums of a tree form a tree on their own
+	for p, n := 0, 0; n < len(numbers); p++ {
+		n += copy(numbers[n:], s.pages[p].sums[:])
+	}
+	Tree(numbers).decode(0)
+
+	return len(numbers)
+}
+
+// SearchSum returns the largest index and corresponding prefix sum that is
+// smaller than or equal to the given value. In case no prefix sum is small
+// enough, -1 is returned. This operation assumes the prefix sums to increase
+// monotonically.
+func (s *Snapshot) SearchSum(value int32) (int, int32) {
+	if s.n == 0 {
+		return -1, 0
+	}
+
+	lo := 0
+	toSearch := value
+	for hi := 1 << (bits.Len(uint(s.n)) - 1); hi != 0; hi >>= 1 {
+		if m := lo + hi; m <= s.n && toSearch >= s.at(m-1) {
+			lo += hi
+			toSearch -= s.at(m - 1)
+		}
+	}
+
+	return lo - 1, value - toSearch
+}
+
+// PagedTree is a Binary Indexed Tree that supports O(1) snapshots. Its
+// partial sums are stored in pages, which are shared between the tree and
+// its snapshots, and copied on the first update after a snapshot. A PagedTree
+// is not safe for concurrent use, but its snapshots are.
+type PagedTree struct {
+	view   Snapshot
+	epoch  uint64 // current snapshot generation
+	shared bool   // the page table is shared with a snapshot
+}
+
+// NewPagedTree creates a PagedTree of n elements, all set to zero.
+func NewPagedTree(n int) *PagedTree {
+	if n < 0 {
+		n = 0
+	}
+
+	t := &PagedTree{view: Snapshot{n: n}}
+	t.view.pages = make([]*page, (n+pageSize-1)>>pageShift)
+	for p := range t.view.pages {
+		t.view.pages[p] = &page{}
+	}
+	return t
+}
+
+// PagedTreeFrom creates a PagedTree from a slice of numbers.
+func PagedTreeFrom(numbers []int32) *PagedTree {
+	t := NewPagedTree(len(numbers))
+	tree := From(numbers)
+	for p, pg := range t.view.pages {
+		copy(pg.sums[:], tree[p<<pageShift:])
+	}
+	return t
+}
+
+// writable returns page p, after copying it when it may be shared
+// with a snapshot.
+func (t *PagedTree) writable(p int) *page {
+	if t.shared {
+		t.view.pages = slices.Clone(t.view.pages)
+		t.shared = false
+	}
+
+	pg := t.view.pages[p]
+	if pg.epoch != t.epoch {
+		cp := *pg
+		cp.epoch = t.epoch
+		pg = &cp
+		t.view.pages[p] = pg
+	}
+	return pg
+}
+
+// Snapshot returns an immutable view of the current state of the tree in
+// O(1) time. Subsequent updates to the tree copy the pages they touch.
+func (t *PagedTree) Snapshot() *Snapshot {
+	t.epoch++
+	t.shared = true
+	s := t.view
+	return &s
+}
+
+// Len returns the number of elements in the tree.
+func (t *PagedTree) Len() int {
+	return t.view.n
+}
+
+// Add adds the given value to the number at index i. If the
+// index is outside of the tree, no value is added.
+func (t *PagedTree) Add(i int, value int32) {
+	if i < 0 {
+		return
+	}
+
+	for i < t.view.n {
+		t.writable(i >> pageShift).sums[i&(pageSize-1)] += value
+		i |= i + 1
+	}
+}
+
+// Set sets a number at a given index. If the index
+// is outside of the tree, no updates are made.
+func (t *PagedTree) Set(i int, number int32) {
+	t.Add(i, number-t.view.Number(i))
+}
+
+// Sum returns the prefix sum at index i of the tree. If i is larger than the
+// largest index, the prefix sum of the largest index is returned.
+func (t *PagedTree) Sum(i int) int32 {
+	return t.view.Sum(i)
+}
+
+// RangeSum returns the sum of the [lo, hi) range. In case of a partial
+// overlap of the range with the tree, RangeSum will return the sum
+// of the intersection of the given interval with the tree.
+func (t *PagedTree) RangeSum(lo, hi int) int32 {
+	return t.view.RangeSum(lo, hi)
+}
+
+// Number returns the element at index i.
+// If i is outside of the tree, 0 will be returned.
+func (t *PagedTree) Number(i int) int32 {
+	return t.view.Number(i)
+}
+
+// Numbers returns all numbers in the tree. If the numbers slice is
+// too short, only numbers up to the length of the slice will be returned.
+func (t *PagedTree) Numbers(numbers []int32) int {
+	return t.view.Numbers(numbers)
+}
+
+// SearchSum returns the largest index and corresponding prefix sum that is
+// smaller than or equal to the given value. In case no prefix sum is small
+// enough, -1 is returned. This operation assumes the prefix sums to increase
+// monotonically.
+func (t *PagedTree) SearchSum(value int32) (int, int32) {
+	return t.view.SearchSum(value)
+}
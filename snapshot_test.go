@@ -0,0 +1,111 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func checkSnapshot(t *testing.T, s *Snapshot, want []int32) {
+	t.Helper()
+
+	if s.Len() != len(want) {
+		t.Fatalf("length got: %d != want: %d\n", s.Len(), len(want))
+	}
+
+	tree := From(want)
+	numbers := make([]int32, len(want)+1)
+	if n := s.Numbers(numbers); n != len(want) {
+		t.Errorf("Numbers got: %d != want: %d\n", n, len(want))
+	}
+
+	for i := range want {
+		if numbers[i] != want[i] {
+			t.Fatalf("index: %d, numbers got: %d != want: %d\n", i, numbers[i], want[i])
+		}
+		if got := s.Number(i); got != want[i] {
+			t.Fatalf("index: %d, number got: %d != want: %d\n", i, got, want[i])
+		}
+		if got := s.Sum(i); got != tree.Sum(i) {
+			t.Fatalf("index: %d, sum got: %d != want: %d\n", i, got, tree.Sum(i))
+		}
+		if got := s.RangeSum(i, len(want)+1); got != tree.RangeSum(i, len(want)) {
+			t.Fatalf("index: %d, range sum got: %d != want: %d\n", i, got, tree.RangeSum(i, len(want)))
+		}
+	}
+
+	for _, value := range []int32{-1, 0, 1, tree.Sum(len(want) / 2), tree.Sum(len(want))} {
+		wi, wsum := tree.SearchSum(value)
+		if gi, gsum := s.SearchSum(value); gi != wi || gsum != wsum {
+			t.Errorf("SearchSum(%d) got: (%d, %d) != want: (%d, %d)\n", value, gi, gsum, wi, wsum)
+		}
+	}
+}
+
+func TestPagedTree(t *testing.T) {
+	empty := NewPagedTree(0)
+	empty.Add(0, 1)
+	checkSnapshot(t, empty.Snapshot(), nil)
+
+	rand.Seed(18)
+	for _, n := range []int{1, 37, pageSize, 3*pageSize + 5} {
+		numbers := make([]int32, n)
+		for i := range numbers {
+			numbers[i] = rand.Int31n(10)
+		}
+
+		tree := PagedTreeFrom(numbers)
+		var snaps []*Snapshot
+		var wants [][]int32
+		for k := 0; k < 8; k++ {
+			snaps = append(snaps, tree.Snapshot())
+			wants = append(wants, append([]int32(nil), numbers...))
+
+			for j := 0; j < 50; j++ {
+				i, num := rand.Intn(n), rand.Int31n(10)
+				if j%2 == 0 {
+					tree.Set(i, num)
+					numbers[i] = num
+				} else {
+					tree.Add(i, num)
+					numbers[i] += num
+				}
+			}
+		}
+
+		if tree.Len() != n {
+			t.Fatalf("length got: %d != want: %d\n", tree.Len(), n)
+		}
+		checkSnapshot(t, &tree.view, numbers)
+		for k, s := range snaps {
+			checkSnapshot(t, s, wants[k])
+		}
+	}
+}
+
+func TestSnapshotConcurrent(t *testing.T) {
+	const n = 2*pageSize + 3
+
+	tree := NewPagedTree(n)
+	for i := 0; i < n; i++ {
+		tree.Set(i, 1)
+	}
+
+	var wg sync.WaitGroup
+	for k := 0; k < 20; k++ {
+		s := tree.Snapshot()
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			if got := s.Sum(n); got != int32(n+k) {
+				t.Errorf("snapshot: %d, sum got: %d != want: %d\n", k, got, n+k)
+			}
+		}(k)
+		tree.Add(k*pageSize%n, 1)
+	}
+	wg.Wait()
+}
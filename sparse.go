@@ -0,0 +1,110 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import "math"
+
+// SparseTree represents a Binary Indexed Tree over the full uint64 index
+// space. Only the partial sums that are non-zero are stored, such that memory
+// use is proportional to the number of updates times log U, with U = 2^64.
+// All operations take O(log U) time. A SparseTree must be created with
+// NewSparseTree, or make, before numbers can be added.
+type SparseTree map[uint64]int32
+
+// NewSparseTree creates an empty SparseTree.
+func NewSparseTree() SparseTree {
+	return make(SparseTree)
+}
+
+// add adds value to the partial sum at index i, and removes the partial sum
+// from the map when it becomes zero.
+func (t SparseTree) add(i uint64, value int32) {
+	if v := t[i] + value; v != 0 {
+		t[i] = v
+	} else {
+		delete(t, i)
+	}
+}
+
+// Add adds the given value to the number at index i.
+func (t SparseTree) Add(i uint64, value int32) {
+	if value == 0 {
+		return
+	}
+
+	// add value to relevant partial sums, up to the last index
+	for {
+		t.add(i, value)
+		if i == math.MaxUint64 {
+			return
+		}
+		i |= i + 1
+	}
+}
+
+// Set sets a number at a given index.
+func (t SparseTree) Set(i uint64, number int32) {
+	t.Add(i, number-t.Number(i))
+}
+
+// Sum returns the prefix sum at index i of the tree.
+func (t SparseTree) Sum(i uint64) int32 {
+	// compute prefix sum at index i by adding relevant partial sums
+	var sum int32
+	for {
+		sum += t[i]
+		if i&(i+1) == 0 {
+			return sum
+		}
+		i = i&(i+1) - 1
+	}
+}
+
+// RangeSum returns the sum of the [lo, hi) range. As the upper bound is not
+// included, the range cannot cover the last index of the index space.
+func (t SparseTree) RangeSum(lo, hi uint64) int32 {
+	if hi <= lo {
+		return 0
+	}
+	if lo == 0 {
+		return t.Sum(hi - 1)
+	}
+	return t.Sum(hi-1) - t.Sum(lo-1)
+}
+
+// Number returns the element at index i.
+func (t SparseTree) Number(i uint64) int32 {
+	// calculate number by subtracting relevant partial sums from t[i]
+	number := t[i]
+	for j, k := i, i&(i+1); k < j; j &= j - 1 {
+		number -= t[j-1]
+	}
+	return number
+}
+
+// SearchSum returns the largest index and corresponding prefix sum that is
+// smaller than or equal to the given value. In case no prefix sum is small
+// enough, ok is false. This operation assumes the prefix sums to increase
+// monotonically.
+func (t SparseTree) SearchSum(value int32) (i uint64, sum int32, ok bool) {
+	// the partial sum at the last index covers the full index space
+	if total := t[math.MaxUint64]; total <= value {
+		return math.MaxUint64, total, true
+	}
+
+	var lo uint64
+	toSearch := value
+	for hi := uint64(1) << 63; hi != 0; hi >>= 1 {
+		if m := lo + hi; toSearch >= t[m-1] {
+			lo += hi
+			toSearch -= t[m-1]
+		}
+	}
+
+	if lo == 0 {
+		return 0, 0, false
+	}
+	return lo - 1, value - toSearch, true
+}
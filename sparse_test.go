@@ -0,0 +1,85 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestSparseTree(t *testing.T) {
+	tree := NewSparseTree()
+	if i, sum, ok := tree.SearchSum(0); i != math.MaxUint64 || sum != 0 || !ok {
+		t.Errorf("empty tree got: (%d, %d, %t)\n", i, sum, ok)
+	}
+	if i, sum, ok := tree.SearchSum(-1); i != 0 || sum != 0 || ok {
+		t.Errorf("empty tree got: (%d, %d, %t)\n", i, sum, ok)
+	}
+
+	rand.Seed(18)
+	numbers := map[uint64]int32{}
+	for _, i := range []uint64{0, 1, 7, 1 << 32, 1<<63 - 1, 1 << 63, math.MaxUint64 - 1, math.MaxUint64} {
+		numbers[i] = 0
+	}
+	for k := 0; k < 50; k++ {
+		numbers[rand.Uint64()>>uint(rand.Intn(64))] = 0
+	}
+
+	var indices []uint64
+	for i := range numbers {
+		indices = append(indices, i)
+	}
+	sort.Slice(indices, func(a, b int) bool { return indices[a] < indices[b] })
+
+	for k := 0; k < 400; k++ {
+		i := indices[rand.Intn(len(indices))]
+		v := rand.Int31n(10)
+		if k%3 == 0 {
+			tree.Set(i, v)
+			numbers[i] = v
+		} else {
+			tree.Add(i, v)
+			numbers[i] += v
+		}
+	}
+
+	var sum int32
+	for j, i := range indices {
+		sum += numbers[i]
+		if got := tree.Number(i); got != numbers[i] {
+			t.Errorf("Number(%d) got: %d != want: %d\n", i, got, numbers[i])
+		}
+		if got := tree.Sum(i); got != sum {
+			t.Errorf("Sum(%d) got: %d != want: %d\n", i, got, sum)
+		}
+		if got := tree.RangeSum(i, i+1); got != numbers[i] && i != math.MaxUint64 {
+			t.Errorf("RangeSum(%d, %d) got: %d != want: %d\n", i, i+1, got, numbers[i])
+		}
+		if 0 < j && i != math.MaxUint64 {
+			if got, want := tree.RangeSum(indices[j-1]+1, i+1), numbers[i]; got != want {
+				t.Errorf("RangeSum(%d, %d) got: %d != want: %d\n", indices[j-1]+1, i+1, got, want)
+			}
+		}
+
+		// the largest index with this prefix sum is just before the next non-zero number
+		want := uint64(math.MaxUint64)
+		for _, next := range indices[j+1:] {
+			if numbers[next] != 0 {
+				want = next - 1
+				break
+			}
+		}
+		if got, gsum, ok := tree.SearchSum(sum); got != want || gsum != sum || !ok {
+			t.Errorf("SearchSum(%d) got: (%d, %d, %t) != want: (%d, %d, true)\n", sum, got, gsum, ok, want, sum)
+		}
+	}
+
+	// every update touches at most 64 partial sums
+	if len(tree) > 64*len(indices) {
+		t.Errorf("nodes got: %d > %d\n", len(tree), 64*len(indices))
+	}
+}
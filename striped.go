@@ -0,0 +1,165 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// cacheLine is the assumed size of a cache line, in bytes.
+const cacheLine = 64
+
+// shardData is a tree with its own lock.
+type shardData struct {
+	mu   sync.Mutex
+	tree Tree
+}
+
+// shard is padded to a multiple of the cache line size, such that the locks
+// of neighboring shards never share a cache line.
+type shard struct {
+	shardData
+	_ [cacheLine - unsafe.Sizeof(shardData{})%cacheLine]byte
+}
+
+// StripedTree is a Binary Indexed Tree for write-heavy concurrent counting.
+// It keeps a number of independent shards, each holding a Tree of the same
+// length and its own lock. Every Add goes to a single shard, such that
+// concurrent writers rarely contend, while queries add up the results of
+// all shards. A query locks one shard at a time, so it does not observe a
+// consistent snapshot of concurrent updates across shards.
+type StripedTree struct {
+	shards []shard
+	hints  sync.Pool     // *int shard hints, cached per P
+	seed   atomic.Uint32 // initial hints, only used when the pool is empty
+}
+
+// NewStripedTree creates a StripedTree of n elements, all set to zero, with
+// the given number of shards. If the number of shards is not positive, it
+// defaults to GOMAXPROCS.
+func NewStripedTree(n, shards int) *StripedTree {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	s := &StripedTree{shards: make([]shard, shards)}
+	for i := range s.shards {
+		s.shards[i].tree = New(n)
+	}
+	return s
+}
+
+// Len returns the number of elements in the tree.
+func (s *StripedTree) Len() int {
+	return len(s.shards[0].tree)
+}
+
+// Shards returns the number of shards.
+func (s *StripedTree) Shards() int {
+	return len(s.shards)
+}
+
+// Add adds the given value to the number at index i. The shard is picked
+// without shared state: every P caches a shard hint, and when that shard is
+// locked, the next free shard is taken and becomes the new hint. If the index
+// is outside of the tree boundaries, no value is added.
+func (s *StripedTree) Add(i int, value int32) {
+	hint, _ := s.hints.Get().(*int)
+	if hint == nil {
+		hint = new(int)
+		*hint = int(s.seed.Add(1))
+	}
+
+	// after a full round of locked shards, wait for the last one
+	k := uint(*hint) % uint(len(s.shards))
+	sh := &s.shards[k]
+	for try := 1; !sh.mu.TryLock(); try++ {
+		if try == len(s.shards) {
+			sh.mu.Lock()
+			break
+		}
+		if k++; k == uint(len(s.shards)) {
+			k = 0
+		}
+		sh = &s.shards[k]
+	}
+	sh.tree.Add(i, value)
+	sh.mu.Unlock()
+
+	*hint = int(k)
+	s.hints.Put(hint)
+}
+
+// AddHint adds the given value to the number at index i, in the shard
+// selected by the hint, modulo the number of shards. Writers that use
+// distinct hints, e.g. a worker id, never contend with each other, as long
+// as there are at least as many shards as writers.
+func (s *StripedTree) AddHint(hint, i int, value int32) {
+	sh := &s.shards[uint(hint)%uint(len(s.shards))]
+	sh.mu.Lock()
+	sh.tree.Add(i, value)
+	sh.mu.Unlock()
+}
+
+// Sum returns the prefix sum at index i, added over all shards. If i is
+// larger than the largest index of the tree, the prefix sum of the largest
+// index is returned.
+func (s *StripedTree) Sum(i int) int32 {
+	var sum int32
+	for k := range s.shards {
+		sh := &s.shards[k]
+		sh.mu.Lock()
+		sum += sh.tree.Sum(i)
+		sh.mu.Unlock()
+	}
+	return sum
+}
+
+// RangeSum returns the sum of the [lo, hi) range, added over all shards.
+func (s *StripedTree) RangeSum(lo, hi int) int32 {
+	var sum int32
+	for k := range s.shards {
+		sh := &s.shards[k]
+		sh.mu.Lock()
+		sum += sh.tree.RangeSum(lo, hi)
+		sh.mu.Unlock()
+	}
+	return sum
+}
+
+// Number returns the element at index i, added over all shards.
+// If i is outside of the tree, 0 will be returned.
+func (s *StripedTree) Number(i int) int32 {
+	var number int32
+	for k := range s.shards {
+		sh := &s.shards[k]
+		sh.mu.Lock()
+		number += sh.tree.Number(i)
+		sh.mu.Unlock()
+	}
+	return number
+}
+
+// Collapse merges all shards into a single, new Tree in O(S·n) time, for S
+// shards. As a tree is linear in its numbers, the partial sums of the shards
+// are simply added. All shards are locked during the merge, such that the
+// result is a consistent snapshot. The shards themselves are left unchanged.
+func (s *StripedTree) Collapse() Tree {
+	for k := range s.shards {
+		s.shards[k].mu.Lock()
+		defer s.shards[k].mu.Unlock()
+	}
+
+	t := New(s.Len())
+	for k := range s.shards {
+		for i, v := range s.shards[k].tree {
+			t[i] += v
+		}
+	}
+	return t
+}
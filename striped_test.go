@@ -0,0 +1,83 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestStripedTree(t *testing.T) {
+	const (
+		n       = 37
+		writers = 8
+		adds    = 300
+	)
+
+	if size := unsafe.Sizeof(shard{}); size%cacheLine != 0 {
+		t.Errorf("shard size got: %d, not a multiple of %d\n", size, cacheLine)
+	}
+
+	s := NewStripedTree(n, 4)
+	if s.Len() != n || s.Shards() != 4 || NewStripedTree(n, 0).Shards() < 1 {
+		t.Fatalf("length got: %d, shards got: %d\n", s.Len(), s.Shards())
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for k := 0; k < adds; k++ {
+				i := (w + 3*k) % n
+				if k%2 == 0 {
+					s.Add(i, int32(i))
+				} else {
+					s.AddHint(w, i, int32(i))
+				}
+			}
+		}(w)
+	}
+
+	// readers run concurrently with the writers, for the race detector
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < adds; k++ {
+				s.Sum(k % n)
+				s.RangeSum(k%n, n)
+				s.Number(k % n)
+			}
+		}()
+	}
+	wg.Wait()
+
+	numbers := make([]int32, n)
+	for w := 0; w < writers; w++ {
+		for k := 0; k < adds; k++ {
+			i := (w + 3*k) % n
+			numbers[i] += int32(i)
+		}
+	}
+
+	want := From(numbers)
+	tree := s.Collapse()
+	for i := range want {
+		if tree[i] != want[i] {
+			t.Errorf("index: %d, partial sum got: %d != want: %d\n", i, tree[i], want[i])
+		}
+		if got := s.Sum(i); got != want.Sum(i) {
+			t.Errorf("index: %d, sum got: %d != want: %d\n", i, got, want.Sum(i))
+		}
+		if got := s.Number(i); got != numbers[i] {
+			t.Errorf("index: %d, number got: %d != want: %d\n", i, got, numbers[i])
+		}
+		if got := s.RangeSum(i, n); got != want.RangeSum(i, n) {
+			t.Errorf("index: %d, range sum got: %d != want: %d\n", i, got, want.RangeSum(i, n))
+		}
+	}
+}
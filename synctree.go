@@ -0,0 +1,391 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import "sync"
+
+// SyncTree is a Binary Indexed Tree that is safe for concurrent use. Queries
+// hold a read lock, such that they run in parallel, while updates hold a write
+// lock. Sequences of operations that must be atomic can be grouped with View
+// and Update. The zero value is an empty tree, ready to use.
+type SyncTree struct {
+	mu   sync.RWMutex
+	tree Tree
+}
+
+// NewSyncTree creates a SyncTree that takes ownership of the given tree.
+// The tree must not be used directly afterwards.
+func NewSyncTree(t Tree) *SyncTree {
+	return &SyncTree{tree: t}
+}
+
+// View calls fn with the tree while holding a read lock, such that no
+// updates are made during the call. The tree must not be modified by fn,
+// nor be retained after fn returns.
+func (s *SyncTree) View(fn func(t Tree)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(s.tree)
+}
+
+// Update calls fn with a pointer to the tree while holding the write lock,
+// such that a sequence of operations in fn is atomic. As fn receives a
+// pointer, it can also change the length of the tree, e.g. with Append or
+// Truncate. The tree must not be retained after fn returns.
+func (s *SyncTree) Update(fn func(t *Tree)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.tree)
+}
+
+// Append adds numbers to the back of the tree, as Append does.
+func (s *SyncTree) Append(number ...int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree = Append(s.tree, number...)
+}
+
+// Copy does a deep copy of the tree into dst, as Copy does.
+func (s *SyncTree) Copy(dst Tree) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Copy(dst, s.tree)
+}
+
+// Len returns the number of elements in the tree.
+func (s *SyncTree) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tree)
+}
+
+// Sum returns the prefix sum at index i, as Tree.Sum does.
+func (s *SyncTree) Sum(i int) int32 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.Sum(i)
+}
+
+// RangeSum returns the sum of the [lo, hi) range, as Tree.RangeSum does.
+func (s *SyncTree) RangeSum(lo, hi int) int32 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.RangeSum(lo, hi)
+}
+
+// SumMany returns the prefix sums at the given indices, as Tree.SumMany does.
+func (s *SyncTree) SumMany(indices []int, out []int32) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.SumMany(indices, out)
+}
+
+// RangeSumMany returns the sums of the given ranges, as Tree.RangeSumMany does.
+func (s *SyncTree) RangeSumMany(lo, hi []int, out []int32) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.RangeSumMany(lo, hi, out)
+}
+
+// Sums returns the prefix sums of the tree, as Tree.Sums does.
+func (s *SyncTree) Sums(sums []int32) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.Sums(sums)
+}
+
+// SumsParallel returns the prefix sums of the tree, using multiple workers, as
+// Tree.SumsParallel does.
+func (s *SyncTree) SumsParallel(sums []int32, workers int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.SumsParallel(sums, workers)
+}
+
+// Number returns the element at index i, as Tree.Number does.
+func (s *SyncTree) Number(i int) int32 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.Number(i)
+}
+
+// RangeNumbers returns the numbers from index lo onwards, as Tree.RangeNumbers
+// does.
+func (s *SyncTree) RangeNumbers(lo int, buf []int32) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.RangeNumbers(lo, buf)
+}
+
+// Numbers returns all numbers in the tree, as Tree.Numbers does.
+func (s *SyncTree) Numbers(numbers []int32) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.Numbers(numbers)
+}
+
+// NumbersParallel returns all numbers in the tree, using multiple workers, as
+// Tree.NumbersParallel does.
+func (s *SyncTree) NumbersParallel(numbers []int32, workers int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.NumbersParallel(numbers, workers)
+}
+
+// SearchSum searches the largest prefix sum smaller than or equal to value, as
+// Tree.SearchSum does.
+func (s *SyncTree) SearchSum(value int32) (int, int32) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.SearchSum(value)
+}
+
+// SearchSumLT searches the largest prefix sum smaller than value, as
+// Tree.SearchSumLT does.
+func (s *SyncTree) SearchSumLT(value int32) (int, int32) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.SearchSumLT(value)
+}
+
+// SearchSumGE searches the smallest prefix sum larger than or equal to value,
+// as Tree.SearchSumGE does.
+func (s *SyncTree) SearchSumGE(value int32) (int, int32) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.SearchSumGE(value)
+}
+
+// SearchSumGT searches the smallest prefix sum larger than value, as
+// Tree.SearchSumGT does.
+func (s *SyncTree) SearchSumGT(value int32) (int, int32) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.SearchSumGT(value)
+}
+
+// SearchRangeSum searches the smallest range sum from index lo that reaches
+// value, as Tree.SearchRangeSum does.
+func (s *SyncTree) SearchRangeSum(lo int, value int32) (int, int32) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.SearchRangeSum(lo, value)
+}
+
+// SearchFunc searches the smallest index for which pred is true, as
+// Tree.SearchFunc does.
+func (s *SyncTree) SearchFunc(pred func(i int, sum int32) bool) (int, int32) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.SearchFunc(pred)
+}
+
+// NextNonZero returns the next index that holds a non-zero number, as
+// Tree.NextNonZero does.
+func (s *SyncTree) NextNonZero(i int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.NextNonZero(i)
+}
+
+// PrevNonZero returns the previous index that holds a non-zero number, as
+// Tree.PrevNonZero does.
+func (s *SyncTree) PrevNonZero(i int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.PrevNonZero(i)
+}
+
+// Rank returns the number of units before index i, as Tree.Rank does.
+func (s *SyncTree) Rank(i int) int32 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.Rank(i)
+}
+
+// Select returns the index that holds unit k, as Tree.Select does.
+func (s *SyncTree) Select(k int32) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.Select(k)
+}
+
+// SelectRange returns the index that holds unit k of the [lo, hi) range, as
+// Tree.SelectRange does.
+func (s *SyncTree) SelectRange(k int32, lo, hi int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.SelectRange(k, lo, hi)
+}
+
+// Set sets a number at a given index, as Tree.Set does.
+func (s *SyncTree) Set(i int, number int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Set(i, number)
+}
+
+// Add adds the given value to the number at index i, as Tree.Add does.
+func (s *SyncTree) Add(i int, value int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Add(i, value)
+}
+
+// AddMany adds the deltas to the numbers at the given indices, as Tree.AddMany
+// does.
+func (s *SyncTree) AddMany(indices []int, deltas []int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.AddMany(indices, deltas)
+}
+
+// Mul multiplies the number at index i with the given value, as Tree.Mul does.
+func (s *SyncTree) Mul(i int, value int32) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree.Mul(i, value)
+}
+
+// Shift increases all numbers in the tree with the given value, as Tree.Shift
+// does.
+func (s *SyncTree) Shift(value int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Shift(value)
+}
+
+// Scale scales all numbers in the tree with the given factor, as Tree.Scale
+// does.
+func (s *SyncTree) Scale(value int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Scale(value)
+}
+
+// ScaleParallel scales all numbers in the tree, using multiple workers, as
+// Tree.ScaleParallel does.
+func (s *SyncTree) ScaleParallel(value int32, workers int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.ScaleParallel(value, workers)
+}
+
+// RangeAdd adds a slice of numbers to the numbers from index i onwards, as
+// Tree.RangeAdd does.
+func (s *SyncTree) RangeAdd(i int, numbers []int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.RangeAdd(i, numbers)
+}
+
+// RangeMul multiplies the numbers from index i onwards with a slice of
+// factors, as Tree.RangeMul does.
+func (s *SyncTree) RangeMul(i int, factors []int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.RangeMul(i, factors)
+}
+
+// RangeSet sets a slice of numbers from index i onwards, as Tree.RangeSet does.
+func (s *SyncTree) RangeSet(i int, numbers []int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.RangeSet(i, numbers)
+}
+
+// RangeShift adds the given value to all numbers in the [lo, hi) range, as
+// Tree.RangeShift does.
+func (s *SyncTree) RangeShift(lo, hi int, value int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.RangeShift(lo, hi, value)
+}
+
+// RangeScale scales all numbers in the [lo, hi) range with the given
+// multiplier, as Tree.RangeScale does.
+func (s *SyncTree) RangeScale(lo, hi int, multiplier int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.RangeScale(lo, hi, multiplier)
+}
+
+// Reset sets the length of the tree to zero, as Tree.Reset does.
+func (s *SyncTree) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Reset()
+}
+
+// Truncate shortens the tree to its first n numbers, as Tree.Truncate does.
+func (s *SyncTree) Truncate(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Truncate(n)
+}
+
+// Pop removes the last number from the tree and returns it, as Tree.Pop does.
+func (s *SyncTree) Pop() int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree.Pop()
+}
+
+// Resize changes the length of the tree to n, as Tree.Resize does.
+func (s *SyncTree) Resize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Resize(n)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (s *SyncTree) MarshalBinary() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.MarshalBinary()
+}
+
+// AppendBinary appends the binary encoding of the tree to b,
+// as Tree.AppendBinary does.
+func (s *SyncTree) AppendBinary(b []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.AppendBinary(b)
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (s *SyncTree) UnmarshalBinary(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree.UnmarshalBinary(data)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s *SyncTree) MarshalJSON() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.MarshalJSON()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *SyncTree) UnmarshalJSON(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree.UnmarshalJSON(data)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (s *SyncTree) MarshalText() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.MarshalText()
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (s *SyncTree) UnmarshalText(text []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree.UnmarshalText(text)
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSyncTreeAPI(t *testing.T) {
+	tree, synced := reflect.TypeOf(&Tree{}), reflect.TypeOf(&SyncTree{})
+	for i := 0; i < tree.NumMethod(); i++ {
+		m := tree.Method(i)
+		s, ok := synced.MethodByName(m.Name)
+		if !ok {
+			t.Errorf("SyncTree misses method %s\n", m.Name)
+			continue
+		}
+
+		// compare the signatures without the receivers
+		want, got := m.Type, s.Type
+		if want.NumIn() != got.NumIn() || want.NumOut() != got.NumOut() {
+			t.Errorf("SyncTree.%s got: %v != want: %v\n", m.Name, got, want)
+			continue
+		}
+		for j := 1; j < want.NumIn(); j++ {
+			if want.In(j) != got.In(j) {
+				t.Errorf("SyncTree.%s got: %v != want: %v\n", m.Name, got, want)
+			}
+		}
+		for j := 0; j < want.NumOut(); j++ {
+			if want.Out(j) != got.Out(j) {
+				t.Errorf("SyncTree.%s got: %v != want: %v\n", m.Name, got, want)
+			}
+		}
+	}
+}
+
+func TestSyncTree(t *testing.T) {
+	var empty SyncTree
+	empty.Append(1, 2, 3)
+	if empty.Len() != 3 || empty.Sum(2) != 6 || empty.Pop() != 3 {
+		t.Errorf("zero value got length: %d, sum: %d\n", empty.Len(), empty.Sum(2))
+	}
+
+	const (
+		n       = 64
+		writers = 8
+		readers = 8
+		rounds  = 200
+	)
+
+	numbers := make([]int32, n)
+	for i := range numbers {
+		numbers[i] = 10
+	}
+	s := NewSyncTree(From(numbers))
+	total := int32(10 * n)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				// Move one unit between two indices and back, which keeps the
+				// total. Each move is a single locked call, such that readers
+				// never observe half a move.
+				i, j := (w+r)%n, (w*r+1)%n
+				s.Update(func(t *Tree) {
+					t.Add(i, -1)
+					t.Add(j, 1)
+				})
+				s.AddMany([]int{i, j}, []int32{1, -1})
+			}
+		}(w)
+	}
+
+	errs := make(chan string, readers*rounds)
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]int32, n)
+			for k := 0; k < rounds; k++ {
+				s.View(func(t Tree) {
+					if sum := t.Sum(n - 1); sum != total {
+						errs <- "View"
+					}
+				})
+				if s.Numbers(buf); s.Len() != n {
+					errs <- "Len"
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Errorf("%s: inconsistent total\n", e)
+	}
+
+	buf := make([]int32, n)
+	s.Numbers(buf)
+	for i, got := range buf {
+		if got != 10 {
+			t.Errorf("index: %d, got: %d != want: 10\n", i, got)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// The JSON and text encodings of a tree hold its numbers, not its partial
+// sums, such that they can be interpreted without knowledge of the tree
+// layout. A tree is encoded as a JSON array of numbers, and as text as a
+// comma separated list of numbers, e.g. "1,2,-3". Decoding rebuilds the
+// partial sums with From.
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t Tree) MarshalJSON() ([]byte, error) {
+	numbers := make([]int32, len(t))
+	t.Numbers(numbers)
+	return json.Marshal(numbers)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// A JSON null leaves the tree unchanged.
+func (t *Tree) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var numbers []int32
+	if err := json.Unmarshal(data, &numbers); err != nil {
+		return err
+	}
+	*t = From(numbers, true)
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (t Tree) MarshalText() ([]byte, error) {
+	numbers := make([]int32, len(t))
+	t.Numbers(numbers)
+
+	var b []byte
+	for i, num := range numbers {
+		if 0 < i {
+			b = append(b, ',')
+		}
+		b = strconv.AppendInt(b, int64(num), 10)
+	}
+	return b, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// Spaces around the numbers are allowed.
+func (t *Tree) UnmarshalText(text []byte) error {
+	numbers, err := parseNumbers(text, false)
+	if err != nil {
+		return err
+	}
+	*t = From(numbers, true)
+	return nil
+}
+
+// WithSums is a tree whose JSON and text encodings also hold the prefix sums,
+// for human inspection. It is encoded as a JSON object with a "numbers" and a
+// "sums" array, and as text as a comma separated list of number:sum pairs,
+// e.g. "1:1,2:3,-3:0". When decoding, the prefix sums must match the numbers.
+// In JSON, the sums may be omitted. A tree can be converted to WithSums, and
+// back, at no cost.
+type WithSums Tree
+
+// withSums is the JSON encoding of WithSums.
+type withSums struct {
+	Numbers []int32 `json:"numbers"`
+	Sums    []int32 `json:"sums"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t WithSums) MarshalJSON() ([]byte, error) {
+	v := withSums{make([]int32, len(t)), make([]int32, len(t))}
+	Tree(t).Numbers(v.Numbers)
+	Tree(t).Sums(v.Sums)
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// A JSON null leaves the tree unchanged.
+func (t *WithSums) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var v withSums
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return t.set(v.Numbers, v.Sums)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (t WithSums) MarshalText() ([]byte, error) {
+	numbers := make([]int32, len(t))
+	Tree(t).Numbers(numbers)
+
+	var b []byte
+	var sum int32
+	for i, num := range numbers {
+		if 0 < i {
+			b = append(b, ',')
+		}
+		sum += num
+		b = strconv.AppendInt(b, int64(num), 10)
+		b = append(b, ':')
+		b = strconv.AppendInt(b, int64(sum), 10)
+	}
+	return b, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (t *WithSums) UnmarshalText(text []byte) error {
+	pairs, err := parseNumbers(text, true)
+	if err != nil {
+		return err
+	}
+
+	// the numbers and sums are interleaved
+	numbers := make([]int32, len(pairs)/2)
+	sums := make([]int32, len(pairs)/2)
+	for k := range numbers {
+		numbers[k], sums[k] = pairs[2*k], pairs[2*k+1]
+	}
+	return t.set(numbers, sums)
+}
+
+// set rebuilds the tree from the numbers, after verifying that
+// the prefix sums, if any, match them.
+func (t *WithSums) set(numbers, sums []int32) error {
+	if len(sums) != 0 && len(sums) != len(numbers) {
+		return fmt.Errorf("%w: %d numbers but %d sums", ErrEncoding, len(numbers), len(sums))
+	}
+
+	var sum int32
+	for i := range sums {
+		if sum += numbers[i]; sums[i] != sum {
+			return fmt.Errorf("%w: sum at index %d is %d, not %d", ErrEncoding, i, sums[i], sum)
+		}
+	}
+
+	*t = WithSums(From(numbers, true))
+	return nil
+}
+
+// parseNumbers parses a comma separated list of numbers. With pairs set,
+// every element is a number:sum pair, and the numbers and sums are returned
+// interleaved.
+func parseNumbers(text []byte, pairs bool) ([]int32, error) {
+	if len(bytes.TrimSpace(text)) == 0 {
+		return []int32{}, nil
+	}
+
+	var numbers []int32
+	for _, field := range bytes.Split(text, []byte{','}) {
+		var sum []byte
+		if pairs {
+			var found bool
+			if field, sum, found = bytes.Cut(field, []byte{':'}); !found {
+				return nil, fmt.Errorf("%w: missing sum in %q", ErrEncoding, field)
+			}
+		}
+
+		num, err := strconv.ParseInt(string(bytes.TrimSpace(field)), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrEncoding, err)
+		}
+		numbers = append(numbers, int32(num))
+
+		if pairs {
+			s, err := strconv.ParseInt(string(bytes.TrimSpace(sum)), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrEncoding, err)
+			}
+			numbers = append(numbers, int32(s))
+		}
+	}
+	return numbers, nil
+}
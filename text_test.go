@@ -0,0 +1,123 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+var (
+	_ json.Marshaler           = Tree{}
+	_ json.Unmarshaler         = &Tree{}
+	_ encoding.TextMarshaler   = Tree{}
+	_ encoding.TextUnmarshaler = &Tree{}
+)
+
+func TestJSON(t *testing.T) {
+	testcases := []struct {
+		numbers []int32
+		json    string
+		sums    string
+	}{
+		{nil, `[]`, `{"numbers":[],"sums":[]}`},
+		{[]int32{5}, `[5]`, `{"numbers":[5],"sums":[5]}`},
+		{[]int32{1, 2, -3, 4}, `[1,2,-3,4]`, `{"numbers":[1,2,-3,4],"sums":[1,3,0,4]}`},
+	}
+
+	for k, tc := range testcases {
+		tree := From(tc.numbers)
+		if got, err := json.Marshal(tree); err != nil || string(got) != tc.json {
+			t.Errorf("Testcase: %d, got: %s != want: %s\n", k, got, tc.json)
+		}
+		if got, err := json.Marshal(WithSums(tree)); err != nil || string(got) != tc.sums {
+			t.Errorf("Testcase: %d, got: %s != want: %s\n", k, got, tc.sums)
+		}
+
+		var decoded Tree
+		if err := json.Unmarshal([]byte(tc.json), &decoded); err != nil || !equalTree(decoded, tree) {
+			t.Errorf("Testcase: %d, got: %v != want: %v, error: %v\n", k, decoded, tree, err)
+		}
+		var sums WithSums
+		if err := json.Unmarshal([]byte(tc.sums), &sums); err != nil || !equalTree(Tree(sums), tree) {
+			t.Errorf("Testcase: %d, got: %v != want: %v, error: %v\n", k, sums, tree, err)
+		}
+	}
+
+	// a tree inside a struct, and the optional sums
+	var v struct {
+		A Tree
+		B WithSums
+	}
+	if err := json.Unmarshal([]byte(`{"A":[1,2],"B":{"numbers":[3,4]}}`), &v); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if v.A.Sum(1) != 3 || Tree(v.B).Sum(1) != 7 {
+		t.Errorf("got: %v, %v\n", v.A, v.B)
+	}
+	if err := json.Unmarshal([]byte(`{"A":null,"B":null}`), &v); err != nil || v.A.Sum(1) != 3 {
+		t.Errorf("null got: %v, error: %v\n", v.A, err)
+	}
+
+	for _, data := range []string{`{"numbers":[1,2],"sums":[1]}`, `{"numbers":[1,2],"sums":[1,2]}`} {
+		var sums WithSums
+		if err := json.Unmarshal([]byte(data), &sums); !errors.Is(err, ErrEncoding) {
+			t.Errorf("data: %s, error got: %v\n", data, err)
+		}
+	}
+	var tree Tree
+	if err := json.Unmarshal([]byte(`[1,2147483648]`), &tree); err == nil {
+		t.Errorf("overflow got: %v\n", tree)
+	}
+}
+
+func TestText(t *testing.T) {
+	testcases := []struct {
+		numbers []int32
+		text    string
+		sums    string
+	}{
+		{nil, ``, ``},
+		{[]int32{5}, `5`, `5:5`},
+		{[]int32{1, 2, -3, 4}, `1,2,-3,4`, `1:1,2:3,-3:0,4:4`},
+	}
+
+	for k, tc := range testcases {
+		tree := From(tc.numbers)
+		if got, err := tree.MarshalText(); err != nil || string(got) != tc.text {
+			t.Errorf("Testcase: %d, got: %s != want: %s\n", k, got, tc.text)
+		}
+		if got, err := WithSums(tree).MarshalText(); err != nil || string(got) != tc.sums {
+			t.Errorf("Testcase: %d, got: %s != want: %s\n", k, got, tc.sums)
+		}
+
+		decoded := Tree{9}
+		if err := decoded.UnmarshalText([]byte(tc.text)); err != nil || !equalTree(decoded, tree) {
+			t.Errorf("Testcase: %d, got: %v != want: %v, error: %v\n", k, decoded, tree, err)
+		}
+		sums := WithSums{9}
+		if err := sums.UnmarshalText([]byte(tc.sums)); err != nil || !equalTree(Tree(sums), tree) {
+			t.Errorf("Testcase: %d, got: %v != want: %v, error: %v\n", k, sums, tree, err)
+		}
+	}
+
+	var tree Tree
+	if err := tree.UnmarshalText([]byte(" 1, 2 ,3 ")); err != nil || tree.Sum(2) != 6 {
+		t.Errorf("spaces got: %v, error: %v\n", tree, err)
+	}
+	for _, text := range []string{"1,,2", "a", "2147483648"} {
+		if err := tree.UnmarshalText([]byte(text)); !errors.Is(err, ErrEncoding) {
+			t.Errorf("text: %q, error got: %v\n", text, err)
+		}
+	}
+	var sums WithSums
+	for _, text := range []string{"1:1,2", "1:1,2:2", "1:x"} {
+		if err := sums.UnmarshalText([]byte(text)); !errors.Is(err, ErrEncoding) {
+			t.Errorf("text: %q, error got: %v\n", text, err)
+		}
+	}
+}
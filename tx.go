@@ -0,0 +1,110 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+// undo is a delta that was added to the partial sum at index i.
+type undo struct {
+	i     int
+	delta int32
+}
+
+// Tx is a transaction on a tree. Updates made through the transaction are
+// applied to the tree immediately, and the delta of every partial sum they
+// touch is recorded in an undo log. Rollback subtracts the recorded deltas,
+// in O(changes) time, while Commit discards them. Savepoints allow to roll
+// back part of a transaction. A transaction does not change the length of
+// its tree, and is not safe for concurrent use.
+type Tx struct {
+	tree Tree
+	log  []undo
+}
+
+// Begin starts a transaction on tree t.
+func Begin(t Tree) *Tx {
+	return &Tx{tree: t}
+}
+
+// add adds delta to the partial sum at index i, and records it.
+func (tx *Tx) add(i int, delta int32) {
+	if delta == 0 {
+		return
+	}
+	tx.tree[i] += delta
+	tx.log = append(tx.log, undo{i, delta})
+}
+
+// Add adds the given value to the number at index i. If the
+// index is outside of the tree, no value is added.
+func (tx *Tx) Add(i int, value int32) {
+	if i < 0 {
+		return
+	}
+	for ; i < len(tx.tree); i |= i + 1 {
+		tx.add(i, value)
+	}
+}
+
+// Set sets a number at a given index. If the index
+// is outside of the tree, no updates are made.
+func (tx *Tx) Set(i int, number int32) {
+	tx.Add(i, number-tx.tree.Number(i))
+}
+
+// Shift increases all numbers in the tree with the given value.
+func (tx *Tx) Shift(value int32) {
+	tx.RangeShift(0, len(tx.tree), value)
+}
+
+// RangeShift adds the given value to all numbers in the [lo, hi) index
+// range of the tree. If lo/hi are outside the boundaries of the tree,
+// the [lo, hi) range will be intersected with the tree range. Only the
+// partial sums that cover part of the range are touched.
+func (tx *Tx) RangeShift(lo, hi int, value int32) {
+	lo, hi = tx.tree.clip(lo, hi)
+	if hi <= lo {
+		return
+	}
+
+	// the partial sum at index i covers the numbers in [i&(i+1), i]
+	for i := lo; i < hi; i++ {
+		tx.add(i, value*int32(i+1-max(i&(i+1), lo)))
+	}
+	for i := (hi - 1) | hi; i < len(tx.tree); i |= i + 1 {
+		tx.add(i, value*int32(hi-max(i&(i+1), lo)))
+	}
+}
+
+// Savepoint returns a savepoint, which marks the current
+// state of the transaction for RollbackTo.
+func (tx *Tx) Savepoint() int {
+	return len(tx.log)
+}
+
+// RollbackTo restores the state of the tree at the given savepoint. Later
+// savepoints are invalidated, while earlier ones remain valid.
+func (tx *Tx) RollbackTo(savepoint int) {
+	if savepoint < 0 {
+		savepoint = 0
+	}
+
+	for k := len(tx.log) - 1; savepoint <= k; k-- {
+		tx.tree[tx.log[k].i] -= tx.log[k].delta
+	}
+	if savepoint < len(tx.log) {
+		tx.log = tx.log[:savepoint]
+	}
+}
+
+// Rollback restores the state of the tree at the start of the transaction.
+// The transaction can be reused afterwards, as if it was just started.
+func (tx *Tx) Rollback() {
+	tx.RollbackTo(0)
+}
+
+// Commit keeps all updates made in the transaction, and discards the undo
+// log. The transaction can be reused afterwards, as if it was just started.
+func (tx *Tx) Commit() {
+	tx.log = tx.log[:0]
+}
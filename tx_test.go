@@ -0,0 +1,96 @@
+// Copyright 2019 Geert Van Gorp. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// License which can be found in the LICENSE file.
+
+package bit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// equalTree reports whether the partial sums of both trees are equal.
+func equalTree(a, b Tree) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTx(t *testing.T) {
+	empty := Begin(New())
+	empty.Add(0, 1)
+	empty.Set(0, 1)
+	empty.Shift(1)
+	empty.RangeShift(-1, 1, 1)
+	empty.Rollback()
+	empty.Commit()
+
+	rand.Seed(18)
+	for _, n := range []int{1, 2, 11, 16, 37} {
+		numbers := make([]int32, n)
+		for i := range numbers {
+			numbers[i] = rand.Int31n(10)
+		}
+
+		tree := From(numbers)
+		want := From(numbers)
+		tx := Begin(tree)
+
+		var savepoints []int
+		var states []Tree
+		for k := 0; k < 40; k++ {
+			if k%8 == 0 {
+				savepoints = append(savepoints, tx.Savepoint())
+				states = append(states, append(Tree(nil), tree...))
+			}
+
+			i, j, v := rand.Intn(n+2)-1, rand.Intn(n+2)-1, rand.Int31n(21)-10
+			switch k % 4 {
+			case 0:
+				tx.Add(i, v)
+				want.Add(i, v)
+			case 1:
+				tx.Set(i, v)
+				want.Set(i, v)
+			case 2:
+				tx.RangeShift(min(i, j), max(i, j), v)
+				want.RangeShift(min(i, j), max(i, j), v)
+			case 3:
+				tx.Shift(v)
+				want.Shift(v)
+			}
+
+			if !equalTree(tree, want) {
+				t.Fatalf("n: %d, step: %d, got: %v != want: %v\n", n, k, tree, want)
+			}
+		}
+
+		// roll back the savepoints from the most recent to the oldest
+		for k := len(savepoints) - 1; 0 <= k; k-- {
+			tx.RollbackTo(savepoints[k])
+			if !equalTree(tree, states[k]) {
+				t.Fatalf("n: %d, savepoint: %d, got: %v != want: %v\n", n, k, tree, states[k])
+			}
+		}
+
+		tx.Add(0, 5)
+		tx.Commit()
+		tx.Rollback()
+		if got := tree.Number(0); got != numbers[0]+5 {
+			t.Errorf("n: %d, committed number got: %d != want: %d\n", n, got, numbers[0]+5)
+		}
+
+		tx.Set(n-1, 7)
+		tx.Rollback()
+		numbers[0] += 5
+		if !equalTree(tree, From(numbers)) {
+			t.Errorf("n: %d, rollback got: %v != want: %v\n", n, tree, From(numbers))
+		}
+	}
+}